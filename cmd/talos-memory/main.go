@@ -0,0 +1,175 @@
+// Command talos-memory exports, imports and migrates chat sessions between memory backends.
+//
+// Usage:
+//
+//	talos-memory export  -from sqlite://talos.db -out sessions.jsonl
+//	talos-memory import  -to postgres://localhost/talos -in sessions.jsonl -verify
+//	talos-memory migrate -from redis://localhost:6379/0 -to mongodb://localhost:27017 -checkpoint migrate.ckpt -verify
+//
+// Backends are selected by URL scheme; -from-type and -to-type override it for plain DSNs.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/barekit/talos/pkg/memory"
+	_ "github.com/barekit/talos/pkg/memory/all"
+	"github.com/barekit/talos/pkg/memory/transfer"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1], os.Args[2:]); err != nil {
+		slog.Error("talos-memory failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: talos-memory export|import|migrate [flags]")
+	os.Exit(2)
+}
+
+func run(ctx context.Context, command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	from := fs.String("from", "", "source connection string")
+	fromType := fs.String("from-type", "", "source memory type, if -from has no URL scheme")
+	to := fs.String("to", "", "destination connection string")
+	toType := fs.String("to-type", "", "destination memory type, if -to has no URL scheme")
+	in := fs.String("in", "-", "file to import, - for stdin")
+	out := fs.String("out", "-", "file to export to, - for stdout")
+	tenants := fs.String("tenants", "", "comma-separated tenants to read, default tenant if empty")
+	batch := fs.Int("batch", 100, "sessions listed per batch")
+	checkpoint := fs.String("checkpoint", "", "file recording finished sessions, to resume interrupted runs")
+	verify := fs.Bool("verify", false, "reload written sessions and compare message counts")
+	_ = fs.Parse(args)
+
+	opts := transfer.Options{
+		BatchSize: *batch,
+		Verify:    *verify,
+		Progress: func(r transfer.Result) {
+			if (r.Sessions+r.Skipped)%100 == 0 {
+				slog.Info("progress", "sessions", r.Sessions, "messages", r.Messages, "skipped", r.Skipped)
+			}
+		},
+	}
+	if *tenants != "" {
+		opts.Tenants = strings.Split(*tenants, ",")
+	}
+	if *checkpoint != "" {
+		opts.Checkpoint = &transfer.FileCheckpoint{Path: *checkpoint}
+	}
+
+	switch command {
+	case "export":
+		src, err := open(ctx, *from, *fromType)
+		if err != nil {
+			return err
+		}
+		defer closeMemory(src)
+		// A resumed export appends to the records written before the interruption.
+		w, err := output(*out, *checkpoint != "")
+		if err != nil {
+			return err
+		}
+		result, err := transfer.Export(ctx, src, w, opts)
+		report(command, result)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	case "import":
+		dst, err := open(ctx, *to, *toType)
+		if err != nil {
+			return err
+		}
+		defer closeMemory(dst)
+		r := io.ReadCloser(os.Stdin)
+		if *in != "-" {
+			if r, err = os.Open(*in); err != nil {
+				return err
+			}
+		}
+		defer r.Close()
+		result, err := transfer.Import(ctx, dst, r, opts)
+		report(command, result)
+		return err
+	case "migrate":
+		src, err := open(ctx, *from, *fromType)
+		if err != nil {
+			return err
+		}
+		defer closeMemory(src)
+		dst, err := open(ctx, *to, *toType)
+		if err != nil {
+			return err
+		}
+		defer closeMemory(dst)
+		result, err := transfer.Migrate(ctx, src, dst, opts)
+		report(command, result)
+		return err
+	default:
+		usage()
+		return nil
+	}
+}
+
+func report(command string, result *transfer.Result) {
+	if result != nil {
+		slog.Info("done", "command", command, "sessions", result.Sessions, "messages", result.Messages,
+			"skipped", result.Skipped, "verified", result.Verified)
+	}
+}
+
+func open(ctx context.Context, conn, typ string) (memory.Memory, error) {
+	if conn == "" {
+		return nil, fmt.Errorf("connection string is required")
+	}
+	m, err := memory.NewFactory(ctx, memory.Config{Type: memory.Type(typ), ConnectionString: conn})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open memory: %w", err)
+	}
+	return m, nil
+}
+
+// closeMemory releases the connections of a memory opened by open.
+func closeMemory(m memory.Memory) {
+	var err error
+	switch c := m.(type) {
+	case interface{ Close() error }:
+		err = c.Close()
+	case interface{ Close(context.Context) error }:
+		err = c.Close(context.Background())
+	}
+	if err != nil {
+		slog.Warn("failed to close memory", "error", err)
+	}
+}
+
+func output(path string, appendOnly bool) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendOnly {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	return os.OpenFile(path, flags, 0o644)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
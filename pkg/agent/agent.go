@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/barekit/talos/pkg/knowledge"
 	"github.com/barekit/talos/pkg/llm"
 	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/entity"
+	"github.com/barekit/talos/pkg/redact"
 	"github.com/barekit/talos/pkg/tools"
 )
 
@@ -22,7 +25,12 @@ type Agent struct {
 	Memory       memory.Memory
 	SessionID    string
 	Knowledge    *knowledge.KnowledgeBase
+	RAG          RAGOptions
+	Redactor     *redact.Redactor
+	Entities     *entity.Memory
 	Debug        bool
+
+	restoreFor map[string]bool
 }
 
 // Option is a function that configures an Agent.
@@ -35,6 +43,7 @@ func New(llmProvider llm.Provider, opts ...Option) *Agent {
 		LLM:      llmProvider,
 		Tools:    make(map[string]*tools.Tool),
 		MaxSteps: 10,
+		RAG:      DefaultRAGOptions(),
 	}
 
 	for _, opt := range opts {
@@ -82,6 +91,15 @@ func WithMemory(mem memory.Memory, sessionID string) Option {
 	}
 }
 
+// WithEntityMemory recalls known facts about the entities mentioned in the input
+// into the prompt, and remembers the entities of every completed turn. Remembering makes
+// an extra model call before Run returns, or before the RunStream channel is closed.
+func WithEntityMemory(em *entity.Memory) Option {
+	return func(a *Agent) {
+		a.Entities = em
+	}
+}
+
 // WithDebug enables debug logging.
 func WithDebug(enable bool) Option {
 	return func(a *Agent) {
@@ -91,15 +109,28 @@ func WithDebug(enable bool) Option {
 
 // Run executes the agent loop with the given input.
 func (a *Agent) Run(ctx context.Context, input string, attachments []llm.Attachment) (string, error) {
+	result, err := a.RunResult(ctx, input, attachments)
+	if err != nil {
+		return "", err
+	}
+	return result.Content, nil
+}
+
+// RunResult executes the agent loop with the given input and returns the answer
+// together with citations for every document retrieved during the run.
+func (a *Agent) RunResult(ctx context.Context, input string, attachments []llm.Attachment) (*Result, error) {
+	recorder := knowledge.NewRecorder()
+	ctx = knowledge.ContextWithRecorder(ctx, recorder)
+
 	if a.Debug {
-		slog.Info("Agent Run started", "input", input, "session_id", a.SessionID)
+		slog.Info("Agent Run started", "input", a.redact(input), "session_id", a.SessionID)
 	}
 
 	if err := a.prepareStep(ctx, input, attachments); err != nil {
 		if a.Debug {
 			slog.Error("Agent Run failed to prepare step", "error", err)
 		}
-		return "", err
+		return nil, err
 	}
 
 	// Prepare tool definitions
@@ -122,31 +153,35 @@ func (a *Agent) Run(ctx context.Context, input string, attachments []llm.Attachm
 			if a.Debug {
 				slog.Error("LLM Chat failed", "error", err)
 			}
-			return "", fmt.Errorf("LLM error: %w", err)
+			return nil, fmt.Errorf("LLM error: %w", err)
 		}
 
 		a.History = append(a.History, *response)
-		if a.Memory != nil && a.SessionID != "" {
-			if err := a.Memory.Save(ctx, a.SessionID, *response); err != nil {
+
+		// If no tool calls, we are done
+		if len(response.ToolCalls) == 0 {
+			if err := a.persist(ctx, *response); err != nil {
 				if a.Debug {
 					slog.Error("failed to save assistant message", "error", err)
 				}
-				return "", fmt.Errorf("failed to save assistant message: %w", err)
+				return nil, fmt.Errorf("failed to save assistant message: %w", err)
 			}
-		}
-
-		// If no tool calls, we are done
-		if len(response.ToolCalls) == 0 {
 			if a.Debug {
-				slog.Info("Agent Run completed", "response", response.Content)
+				slog.Info("Agent Run completed", "response", a.redact(response.Content))
 			}
-			return response.Content, nil
+			a.rememberEntities(ctx, input, *response)
+			return &Result{
+				Content:   response.Content,
+				Citations: newCitations(recorder.Documents(), response.Content),
+			}, nil
 		}
 
-		// Act
+		// Act. The tool call and its results are persisted together once all tools ran,
+		// so an interrupted step never leaves a tool call without results in memory.
+		step := []llm.Message{*response}
 		for _, tc := range response.ToolCalls {
 			if a.Debug {
-				slog.Info("Agent Tool Call", "tool", tc.Function.Name, "args", tc.Function.Arguments)
+				slog.Info("Agent Tool Call", "tool", tc.Function.Name, "args", a.redact(tc.Function.Arguments))
 			}
 
 			tool, ok := a.Tools[tc.Function.Name]
@@ -159,21 +194,20 @@ func (a *Agent) Run(ctx context.Context, input string, attachments []llm.Attachm
 					ToolCallID: tc.ID,
 				}
 				a.History = append(a.History, resultMsg)
-				if a.Memory != nil && a.SessionID != "" {
-					_ = a.Memory.Save(ctx, a.SessionID, resultMsg)
-				}
+				step = append(step, resultMsg)
 				continue
 			}
 
 			// Execute tool
-			output, err := tool.Call(tc.Function.Arguments)
+			output, err := tool.CallContext(ctx, a.toolArguments(tc))
 			if err != nil {
 				output = fmt.Sprintf("Error executing tool: %v", err)
-				if a.Debug {
-					slog.Error("Tool execution failed", "tool", tc.Function.Name, "error", err)
-				}
-			} else {
-				if a.Debug {
+			}
+			output = a.redact(output)
+			if a.Debug {
+				if err != nil {
+					slog.Error("Tool execution failed", "tool", tc.Function.Name, "error", a.redact(err.Error()))
+				} else {
 					slog.Info("Tool execution successful", "tool", tc.Function.Name, "output", output)
 				}
 			}
@@ -185,28 +219,29 @@ func (a *Agent) Run(ctx context.Context, input string, attachments []llm.Attachm
 				ToolCallID: tc.ID,
 			}
 			a.History = append(a.History, resultMsg)
-			if a.Memory != nil && a.SessionID != "" {
-				if err := a.Memory.Save(ctx, a.SessionID, resultMsg); err != nil {
-					if a.Debug {
-						slog.Error("failed to save tool output", "error", err)
-					}
-					return "", fmt.Errorf("failed to save tool output: %w", err)
-				}
+			step = append(step, resultMsg)
+		}
+
+		if err := a.persist(ctx, step...); err != nil {
+			if a.Debug {
+				slog.Error("failed to save tool step", "error", err)
 			}
+			return nil, fmt.Errorf("failed to save tool step: %w", err)
 		}
 	}
 
 	if a.Debug {
 		slog.Error("Agent Run max steps reached")
 	}
-	return "", fmt.Errorf("max steps reached")
+	return nil, fmt.Errorf("max steps reached")
 }
 
 // RunStream executes the agent loop and returns a stream of response chunks.
-// Note: Currently supports single-turn streaming (no tool execution loop).
+// Note: Currently supports single-turn streaming (no tool execution loop), and returns
+// no citations; use RunResult to get the documents an answer is based on.
 func (a *Agent) RunStream(ctx context.Context, input string, attachments []llm.Attachment) (<-chan string, error) {
 	if a.Debug {
-		slog.Info("Agent RunStream started", "input", input, "session_id", a.SessionID)
+		slog.Info("Agent RunStream started", "input", a.redact(input), "session_id", a.SessionID)
 	}
 
 	if err := a.prepareStep(ctx, input, attachments); err != nil {
@@ -245,11 +280,10 @@ func (a *Agent) RunStream(ctx context.Context, input string, attachments []llm.A
 			Content: fullResponse,
 		}
 		a.History = append(a.History, assistantMsg)
-		if a.Memory != nil && a.SessionID != "" {
-			if err := a.Memory.Save(ctx, a.SessionID, assistantMsg); err != nil && a.Debug {
-				slog.Error("failed to save assistant message", "error", err)
-			}
+		if err := a.persist(ctx, assistantMsg); err != nil && a.Debug {
+			slog.Error("failed to save assistant message", "error", err)
 		}
+		a.rememberEntities(ctx, input, assistantMsg)
 	}()
 
 	return out, nil
@@ -257,7 +291,10 @@ func (a *Agent) RunStream(ctx context.Context, input string, attachments []llm.A
 
 // prepareStep handles common logic for preparing the agent step:
 // loading history, retrieving RAG context, and saving user input.
+// The messages it adds are persisted with a single batch save.
 func (a *Agent) prepareStep(ctx context.Context, input string, attachments []llm.Attachment) error {
+	var pending []llm.Message
+
 	// Load history from memory if available
 	if a.Memory != nil && a.SessionID != "" {
 		history, err := a.Memory.Load(ctx, a.SessionID)
@@ -274,42 +311,85 @@ func (a *Agent) prepareStep(ctx context.Context, input string, attachments []llm
 			Content: a.Instructions,
 		}
 		a.History = append(a.History, sysMsg)
-		if a.Memory != nil && a.SessionID != "" {
-			_ = a.Memory.Save(ctx, a.SessionID, sysMsg)
-		}
+		pending = append(pending, sysMsg)
 	}
 
+	// Personal data must not reach the model, query transformers, embedders or memory.
+	input = a.redact(input)
+
 	// RAG: Retrieve relevant documents if Knowledge is set
 	var contextInfo string
 	if a.Knowledge != nil {
-		docs, err := a.Knowledge.Retrieve(ctx, input, 3)
+		info, err := a.retrieveContext(ctx, input)
 		if err != nil {
-			return fmt.Errorf("failed to retrieve documents: %w", err)
-		}
-		if len(docs) > 0 {
-			contextInfo = "\nRelevant Context:\n"
-			for _, doc := range docs {
-				contextInfo += fmt.Sprintf("- %s\n", doc.Content)
-			}
+			return err
 		}
+		contextInfo = info
 	}
 
-	fullInput := input
-	if contextInfo != "" {
-		fullInput += contextInfo
+	if a.Entities != nil {
+		facts, err := a.Entities.Recall(ctx, input)
+		if err != nil {
+			return err
+		}
+		if facts != "" {
+			a.History = append(a.History, llm.Message{Role: llm.RoleSystem, Content: strings.TrimSpace(facts)})
+		}
 	}
 
 	userMsg := llm.Message{
 		Role:        llm.RoleUser,
-		Content:     fullInput,
+		Content:     input,
 		Attachments: attachments,
 	}
-	a.History = append(a.History, userMsg)
-	if a.Memory != nil && a.SessionID != "" {
-		if err := a.Memory.Save(ctx, a.SessionID, userMsg); err != nil {
-			return fmt.Errorf("failed to save user message: %w", err)
+	// savedMsg is what gets persisted; it only differs when context stays out of memory.
+	savedMsg := userMsg
+
+	if contextInfo != "" {
+		switch a.RAG.Placement {
+		case PlacementSystemMessage:
+			contextMsg := llm.Message{
+				Role:    llm.RoleSystem,
+				Content: strings.TrimSpace(contextInfo),
+			}
+			a.History = append(a.History, contextMsg)
+			if !a.RAG.SkipPersistContext {
+				pending = append(pending, contextMsg)
+			}
+		default:
+			userMsg.Content += contextInfo
+			if !a.RAG.SkipPersistContext {
+				savedMsg = userMsg
+			}
 		}
 	}
 
+	a.History = append(a.History, userMsg)
+	if err := a.persist(ctx, append(pending, savedMsg)...); err != nil {
+		return fmt.Errorf("failed to save user message: %w", err)
+	}
+
 	return nil
 }
+
+// persist appends msgs to the session memory in one batch, if memory is configured.
+func (a *Agent) persist(ctx context.Context, msgs ...llm.Message) error {
+	if a.Memory == nil || a.SessionID == "" {
+		return nil
+	}
+	return memory.SaveBatch(ctx, a.Memory, a.SessionID, msgs)
+}
+
+// rememberEntities stores the entities of a completed turn. Failures do not fail the run.
+func (a *Agent) rememberEntities(ctx context.Context, input string, response llm.Message) {
+	if a.Entities == nil {
+		return
+	}
+	turn := []llm.Message{
+		{Role: llm.RoleUser, Content: a.redact(input)},
+		response,
+	}
+	if err := a.Entities.Remember(ctx, a.SessionID, turn); err != nil && a.Debug {
+		slog.Error("failed to remember entities", "error", err)
+	}
+}
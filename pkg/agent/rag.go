@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/query"
+	"github.com/barekit/talos/pkg/llm"
+)
+
+// ContextPlacement controls where retrieved context is injected into the conversation.
+type ContextPlacement string
+
+const (
+	// PlacementUserMessage appends the retrieved context to the user message.
+	PlacementUserMessage ContextPlacement = "user"
+	// PlacementSystemMessage adds the retrieved context as a system message before the user message.
+	PlacementSystemMessage ContextPlacement = "system"
+)
+
+// QuerySource controls which text is used to query the knowledge base.
+type QuerySource string
+
+const (
+	// QuerySourceInput uses the raw user input as the retrieval query.
+	QuerySourceInput QuerySource = "input"
+	// QuerySourceRewritten uses the query produced by RAGOptions.QueryRewriter.
+	QuerySourceRewritten QuerySource = "rewritten"
+	// QuerySourceTransformed retrieves with every query produced by RAGOptions.Transformer
+	// and fuses the results.
+	QuerySourceTransformed QuerySource = "transformed"
+)
+
+// DefaultContextTemplate is the template used to render retrieved documents.
+const DefaultContextTemplate = `
+Relevant Context:
+{{range .Documents}}- {{.Content}}
+{{end}}`
+
+// QueryRewriter turns the user input into a retrieval query, optionally using the conversation so far.
+type QueryRewriter interface {
+	Rewrite(ctx context.Context, input string, history []llm.Message) (string, error)
+}
+
+// RAGOptions configures how the agent retrieves and injects knowledge.
+type RAGOptions struct {
+	// TopK is the number of documents to retrieve.
+	TopK int
+	// ScoreThreshold drops documents with a lower similarity score. Zero disables the filter.
+	ScoreThreshold float32
+	// PromptTemplate is a text/template rendered with .Query and .Documents. .Query is the
+	// query used for retrieval: the input, the rewritten query, or the transformed queries
+	// joined by newlines.
+	PromptTemplate string
+	// Placement controls where the rendered context goes.
+	Placement ContextPlacement
+	// SkipPersistContext keeps the retrieved context out of memory. By default it is
+	// saved along with the user message.
+	SkipPersistContext bool
+	// QuerySource selects the raw input or a rewritten query for retrieval.
+	QuerySource QuerySource
+	// QueryRewriter is required when QuerySource is QuerySourceRewritten.
+	QueryRewriter QueryRewriter
+	// Transformer is required when QuerySource is QuerySourceTransformed.
+	Transformer query.Transformer
+
+	// template is PromptTemplate parsed by WithRAGOptions.
+	template *template.Template
+}
+
+// DefaultRAGOptions returns the options used when none are configured.
+func DefaultRAGOptions() RAGOptions {
+	return RAGOptions{
+		TopK:           3,
+		PromptTemplate: DefaultContextTemplate,
+		Placement:      PlacementUserMessage,
+		QuerySource:    QuerySourceInput,
+		template:       template.Must(template.New("rag").Parse(DefaultContextTemplate)),
+	}
+}
+
+// WithRAGOptions sets the retrieval options for the agent.
+// Zero values for TopK, PromptTemplate, Placement and QuerySource fall back to the defaults.
+// It panics if PromptTemplate does not parse, so a bad template fails when the agent is built.
+func WithRAGOptions(opts RAGOptions) Option {
+	return func(a *Agent) {
+		defaults := DefaultRAGOptions()
+		if opts.TopK <= 0 {
+			opts.TopK = defaults.TopK
+		}
+		if opts.PromptTemplate == "" {
+			opts.PromptTemplate = defaults.PromptTemplate
+		}
+		if opts.Placement == "" {
+			opts.Placement = defaults.Placement
+		}
+		if opts.QuerySource == "" {
+			opts.QuerySource = defaults.QuerySource
+		}
+		tmpl, err := template.New("rag").Parse(opts.PromptTemplate)
+		if err != nil {
+			panic(fmt.Sprintf("agent: failed to parse RAG prompt template: %v", err))
+		}
+		opts.template = tmpl
+		a.RAG = opts
+	}
+}
+
+// retrieve fetches documents for the input according to the configured query source.
+// It also returns the query that was used, for rendering.
+func (a *Agent) retrieve(ctx context.Context, input string) ([]knowledge.Document, string, error) {
+	switch a.RAG.QuerySource {
+	case QuerySourceInput, "":
+		docs, err := a.Knowledge.Retrieve(ctx, input, a.RAG.TopK)
+		return docs, input, err
+	case QuerySourceRewritten:
+		if a.RAG.QueryRewriter == nil {
+			return nil, "", fmt.Errorf("query source %q requires a query rewriter", a.RAG.QuerySource)
+		}
+		rewritten, err := a.RAG.QueryRewriter.Rewrite(ctx, input, a.History)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to rewrite query: %w", err)
+		}
+		if a.Debug {
+			slog.Info("RAG query rewritten", "input", input, "query", rewritten)
+		}
+		docs, err := a.Knowledge.Retrieve(ctx, rewritten, a.RAG.TopK)
+		return docs, rewritten, err
+	case QuerySourceTransformed:
+		if a.RAG.Transformer == nil {
+			return nil, "", fmt.Errorf("query source %q requires a query transformer", a.RAG.QuerySource)
+		}
+		queries, err := a.RAG.Transformer.Transform(ctx, input, a.History)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to transform query: %w", err)
+		}
+		if len(queries) == 0 {
+			queries = []string{input}
+		}
+		if a.Debug {
+			slog.Info("RAG query transformed", "input", input, "queries", queries)
+		}
+		docs, err := query.RetrieveQueries(ctx, a.Knowledge, queries, a.RAG.TopK)
+		return docs, strings.Join(queries, "\n"), err
+	default:
+		return nil, "", fmt.Errorf("unsupported query source: %s", a.RAG.QuerySource)
+	}
+}
+
+// retrieveContext queries the knowledge base and renders the retrieved documents.
+// It returns an empty string when nothing relevant was found.
+func (a *Agent) retrieveContext(ctx context.Context, input string) (string, error) {
+	docs, usedQuery, err := a.retrieve(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve documents: %w", err)
+	}
+
+	if a.RAG.ScoreThreshold > 0 {
+		var filtered []knowledge.Document
+		for _, doc := range docs {
+			if doc.Score >= a.RAG.ScoreThreshold {
+				filtered = append(filtered, doc)
+			}
+		}
+		docs = filtered
+	}
+
+	if len(docs) == 0 {
+		return "", nil
+	}
+
+	knowledge.RecordRetrieved(ctx, docs...)
+	return renderContext(a.RAG, usedQuery, docs)
+}
+
+// renderContext renders docs with the parsed template, or parses PromptTemplate
+// when the options were assigned without WithRAGOptions.
+func renderContext(opts RAGOptions, usedQuery string, docs []knowledge.Document) (string, error) {
+	tmpl := opts.template
+	if tmpl == nil {
+		var err error
+		if tmpl, err = template.New("rag").Parse(opts.PromptTemplate); err != nil {
+			return "", fmt.Errorf("failed to parse RAG prompt template: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	data := struct {
+		Query     string
+		Documents []knowledge.Document
+	}{
+		Query:     usedQuery,
+		Documents: docs,
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render RAG prompt template: %w", err)
+	}
+	return buf.String(), nil
+}
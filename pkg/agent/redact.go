@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/redact"
+)
+
+// WithRedactor redacts user input before it reaches the model or memory, tool outputs
+// before they re-enter the conversation, and the values written by debug logs.
+// With a tokenizing redactor, the tools named in restoreFor receive their arguments
+// with tokens replaced by the original values.
+func WithRedactor(r *redact.Redactor, restoreFor ...string) Option {
+	return func(a *Agent) {
+		a.Redactor = r
+		a.restoreFor = make(map[string]bool, len(restoreFor))
+		for _, name := range restoreFor {
+			a.restoreFor[name] = true
+		}
+	}
+}
+
+// redact applies the agent's redactor, if any.
+func (a *Agent) redact(s string) string {
+	if a.Redactor == nil {
+		return s
+	}
+	return a.Redactor.Redact(s)
+}
+
+// toolArguments returns the arguments a tool is called with.
+func (a *Agent) toolArguments(tc llm.ToolCall) string {
+	if a.Redactor == nil || !a.restoreFor[tc.Function.Name] {
+		return tc.Function.Arguments
+	}
+	return a.Redactor.Restore(tc.Function.Arguments)
+}
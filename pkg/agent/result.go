@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/barekit/talos/pkg/knowledge"
+)
+
+// CitationTemplate renders retrieved documents with their IDs and asks the model to cite them.
+// Use it as RAGOptions.PromptTemplate to get Citation.Referenced populated.
+const CitationTemplate = `
+Relevant Context (cite the sources you use by their ID in square brackets, e.g. [doc-1]):
+{{range .Documents}}- [{{.ID}}] {{.Content}}
+{{end}}`
+
+// Result is the outcome of an agent run.
+type Result struct {
+	// Content is the final assistant answer.
+	Content string
+	// Citations lists every document supplied to the model during the run.
+	Citations []Citation
+}
+
+// Citation points an answer back to a retrieved document.
+type Citation struct {
+	DocumentID string
+	// Source and URL are copied from the document metadata when present.
+	Source   string
+	URL      string
+	Metadata map[string]interface{}
+	Score    float32
+	// Span is the text that was given to the model.
+	Span Span
+	// Referenced reports whether the answer cites the document as [DocumentID].
+	Referenced bool
+}
+
+// Span is the portion of a source document used in an answer.
+// Start and End are character offsets into the source, or -1 when unknown.
+type Span struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// newCitations builds citations for the documents retrieved during a run.
+func newCitations(docs []knowledge.Document, answer string) []Citation {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	citations := make([]Citation, len(docs))
+	for i, doc := range docs {
+		citations[i] = Citation{
+			DocumentID: doc.ID,
+			Source:     metadataString(doc.Metadata, knowledge.MetadataSource),
+			URL:        metadataString(doc.Metadata, knowledge.MetadataURL),
+			Metadata:   doc.Metadata,
+			Score:      doc.Score,
+			Span: Span{
+				Text:  doc.Content,
+				Start: metadataInt(doc.Metadata, knowledge.MetadataStart),
+				End:   metadataInt(doc.Metadata, knowledge.MetadataEnd),
+			},
+			Referenced: strings.Contains(answer, "["+doc.ID+"]"),
+		}
+	}
+	return citations
+}
+
+func metadataString(metadata map[string]interface{}, key string) string {
+	if v, ok := metadata[key]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case float32:
+		return int(v)
+	default:
+		return -1
+	}
+}
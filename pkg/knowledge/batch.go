@@ -0,0 +1,276 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EmbeddingCache stores vectors keyed by a content hash.
+type EmbeddingCache interface {
+	// Get returns the cached vector for key. The bool is false on a miss.
+	Get(ctx context.Context, key string) ([]float32, bool, error)
+	// Set stores the vector for key.
+	Set(ctx context.Context, key string, vector []float32) error
+}
+
+// BatchEmbedder decorates an Embedder with batching, bounded concurrency, retries and caching.
+type BatchEmbedder struct {
+	embedder    Embedder
+	batchSize   int
+	maxTokens   int
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	cache       EmbeddingCache
+	namespace   string
+}
+
+// BatchOption configures a BatchEmbedder.
+type BatchOption func(*BatchEmbedder)
+
+// WithBatchSize sets the maximum number of texts per Embed call.
+func WithBatchSize(size int) BatchOption {
+	return func(b *BatchEmbedder) {
+		b.batchSize = size
+	}
+}
+
+// WithMaxBatchTokens sets an approximate token budget per Embed call.
+// Tokens are estimated as one per four bytes of text.
+func WithMaxBatchTokens(tokens int) BatchOption {
+	return func(b *BatchEmbedder) {
+		b.maxTokens = tokens
+	}
+}
+
+// WithConcurrency sets how many batches are embedded in parallel.
+func WithConcurrency(n int) BatchOption {
+	return func(b *BatchEmbedder) {
+		b.concurrency = n
+	}
+}
+
+// WithRetries sets how many times a failed batch is retried, with exponential backoff starting at backoff.
+func WithRetries(retries int, backoff time.Duration) BatchOption {
+	return func(b *BatchEmbedder) {
+		b.retries = retries
+		b.backoff = backoff
+	}
+}
+
+// WithCache caches vectors by content hash. The namespace, typically the embedding model name,
+// is part of every key so caches can be shared between models.
+func WithCache(cache EmbeddingCache, namespace string) BatchOption {
+	return func(b *BatchEmbedder) {
+		b.cache = cache
+		b.namespace = namespace
+	}
+}
+
+// NewBatchEmbedder wraps an Embedder.
+func NewBatchEmbedder(embedder Embedder, opts ...BatchOption) *BatchEmbedder {
+	b := &BatchEmbedder{
+		embedder:    embedder,
+		batchSize:   100,
+		concurrency: 4,
+		retries:     3,
+		backoff:     500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Embed generates embeddings for the given texts, serving cached vectors where possible.
+func (b *BatchEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+
+	// Resolve cache hits and collect the texts that still need embedding.
+	// Identical texts are only embedded once.
+	pending := make(map[string][]int)
+	var missing []int
+	for i, text := range texts {
+		keys[i] = b.cacheKey(text)
+		if b.cache != nil {
+			vec, ok, err := b.cache.Get(ctx, keys[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+			}
+			if ok {
+				vectors[i] = vec
+				continue
+			}
+		}
+		if _, seen := pending[keys[i]]; !seen {
+			missing = append(missing, i)
+		}
+		pending[keys[i]] = append(pending[keys[i]], i)
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	batches := b.split(texts, missing)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, max(b.concurrency, 1))
+
+	for _, batch := range batches {
+		wg.Add(1)
+		go func(batch []int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			inputs := make([]string, len(batch))
+			for j, idx := range batch {
+				inputs[j] = texts[idx]
+			}
+
+			result, err := b.embedWithRetry(ctx, inputs)
+			if err == nil && len(result) != len(batch) {
+				err = fmt.Errorf("embedder returned %d vectors for %d texts", len(result), len(batch))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for j, idx := range batch {
+				for _, dup := range pending[keys[idx]] {
+					vectors[dup] = result[j]
+				}
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if b.cache != nil {
+		for _, idx := range missing {
+			if err := b.cache.Set(ctx, keys[idx], vectors[idx]); err != nil {
+				return nil, fmt.Errorf("failed to write embedding cache: %w", err)
+			}
+		}
+	}
+
+	return vectors, nil
+}
+
+// split groups text indexes into batches honouring the size and token limits.
+func (b *BatchEmbedder) split(texts []string, indexes []int) [][]int {
+	var batches [][]int
+	var current []int
+	tokens := 0
+
+	for _, idx := range indexes {
+		estimate := len(texts[idx])/4 + 1
+		full := b.batchSize > 0 && len(current) >= b.batchSize
+		overBudget := b.maxTokens > 0 && len(current) > 0 && tokens+estimate > b.maxTokens
+		if full || overBudget {
+			batches = append(batches, current)
+			current = nil
+			tokens = 0
+		}
+		current = append(current, idx)
+		tokens += estimate
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func (b *BatchEmbedder) embedWithRetry(ctx context.Context, texts []string) ([][]float32, error) {
+	backoff := b.backoff
+	var err error
+	for attempt := 0; attempt <= b.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var vectors [][]float32
+		vectors, err = b.embedder.Embed(ctx, texts)
+		if err == nil {
+			return vectors, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to embed batch after %d attempts: %w", b.retries+1, err)
+}
+
+func (b *BatchEmbedder) cacheKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	key := hex.EncodeToString(sum[:])
+	if b.namespace != "" {
+		key = b.namespace + ":" + key
+	}
+	return key
+}
+
+// MemoryCache is an in-process EmbeddingCache.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	vectors map[string][]float32
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{vectors: make(map[string][]float32)}
+}
+
+// Get implements EmbeddingCache.
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	vec, ok := c.vectors[key]
+	if !ok {
+		return nil, false, nil
+	}
+	// Vectors are copied in and out so callers cannot modify the cached ones.
+	return append([]float32(nil), vec...), true, nil
+}
+
+// Set implements EmbeddingCache.
+func (c *MemoryCache) Set(ctx context.Context, key string, vector []float32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.vectors[key] = append([]float32(nil), vector...)
+	return nil
+}
@@ -0,0 +1,29 @@
+// Package cache provides persistent knowledge.EmbeddingCache implementations.
+package cache
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// encode serializes a vector as little-endian float32 values.
+func encode(vector []float32) []byte {
+	b := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+// decode is the inverse of encode.
+func decode(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector encoding: %d bytes", len(b))
+	}
+	vector := make([]float32, len(b)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vector, nil
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileCache stores one file per vector in a directory.
+type FileCache struct {
+	dir string
+}
+
+// NewFile creates a FileCache in dir, creating the directory if needed.
+func NewFile(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+// Get implements knowledge.EmbeddingCache.
+func (c *FileCache) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	b, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	vector, err := decode(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, true, nil
+}
+
+// Set implements knowledge.EmbeddingCache.
+// Files are written to a temporary name and renamed so readers never see partial vectors.
+func (c *FileCache) Set(ctx context.Context, key string, vector []float32) error {
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(encode(vector)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// path maps a key to a file in the cache directory. Keys with characters that are
+// not safe in file names, such as a namespace containing "/", are hashed.
+func (c *FileCache) path(key string) string {
+	name := strings.ReplaceAll(key, ":", "_")
+	if strings.IndexFunc(name, unsafeRune) >= 0 {
+		sum := sha256.Sum256([]byte(key))
+		name = hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(c.dir, name+".vec")
+}
+
+func unsafeRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache stores vectors as binary strings under "{prefix}{key}".
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedis creates a RedisCache. A zero ttl keeps entries forever.
+func NewRedis(client *redis.Client, prefix string, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Get implements knowledge.EmbeddingCache.
+func (c *RedisCache) Get(ctx context.Context, key string) ([]float32, bool, error) {
+	b, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	vector, err := decode(b)
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, true, nil
+}
+
+// Set implements knowledge.EmbeddingCache.
+func (c *RedisCache) Set(ctx context.Context, key string, vector []float32) error {
+	return c.client.Set(ctx, c.prefix+key, encode(vector), c.ttl).Err()
+}
@@ -0,0 +1,199 @@
+// Package eval measures retrieval quality over a labeled set of queries.
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/barekit/talos/pkg/knowledge"
+)
+
+// Query is a labeled evaluation query.
+type Query struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Relevant lists the IDs of relevant documents.
+	Relevant []string `json:"relevant,omitempty"`
+	// Grades optionally assigns graded relevance for nDCG. It takes precedence over Relevant.
+	Grades map[string]float64 `json:"grades,omitempty"`
+}
+
+func (q Query) relevance() Relevance {
+	if len(q.Grades) > 0 {
+		return Relevance(q.Grades)
+	}
+	r := make(Relevance, len(q.Relevant))
+	for _, id := range q.Relevant {
+		r[id] = 1
+	}
+	return r
+}
+
+// LoadQueries reads queries as JSON Lines, one Query per line.
+func LoadQueries(r io.Reader) ([]Query, error) {
+	var queries []Query
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var q Query
+		if err := json.Unmarshal([]byte(text), &q); err != nil {
+			return nil, fmt.Errorf("failed to parse query on line %d: %w", line, err)
+		}
+		if q.ID == "" {
+			q.ID = fmt.Sprintf("q%d", line)
+		}
+		queries = append(queries, q)
+	}
+	return queries, scanner.Err()
+}
+
+// Retriever is anything that retrieves documents for a query, such as a *knowledge.KnowledgeBase.
+type Retriever interface {
+	Retrieve(ctx context.Context, query string, limit int) ([]knowledge.Document, error)
+}
+
+// RetrieverFunc adapts a function to the Retriever interface.
+type RetrieverFunc func(ctx context.Context, query string, limit int) ([]knowledge.Document, error)
+
+// Retrieve calls f.
+func (f RetrieverFunc) Retrieve(ctx context.Context, query string, limit int) ([]knowledge.Document, error) {
+	return f(ctx, query, limit)
+}
+
+// Config is a named retrieval configuration to evaluate.
+type Config struct {
+	Name      string
+	Retriever Retriever
+}
+
+// AtK holds the cutoff metrics for one k.
+type AtK struct {
+	K         int     `json:"k"`
+	Recall    float64 `json:"recall"`
+	Precision float64 `json:"precision"`
+	NDCG      float64 `json:"ndcg"`
+}
+
+// QueryResult is the outcome of a single query.
+type QueryResult struct {
+	QueryID   string   `json:"query_id"`
+	Retrieved []string `json:"retrieved"`
+	RR        float64  `json:"rr"`
+	AtK       []AtK    `json:"at_k"`
+}
+
+// ConfigResult aggregates the metrics of one configuration, averaged over queries.
+type ConfigResult struct {
+	Name    string        `json:"name"`
+	MRR     float64       `json:"mrr"`
+	AtK     []AtK         `json:"at_k"`
+	Queries []QueryResult `json:"queries,omitempty"`
+}
+
+// Report is the result of an evaluation run.
+type Report struct {
+	Ks      []int          `json:"ks"`
+	Configs []ConfigResult `json:"configs"`
+}
+
+// Run evaluates every configuration against the queries at the given cutoffs.
+// Each query retrieves max(ks) documents once; the cutoffs are applied to that list.
+func Run(ctx context.Context, queries []Query, configs []Config, ks []int) (*Report, error) {
+	if len(ks) == 0 {
+		return nil, fmt.Errorf("at least one k is required")
+	}
+	ks = append([]int(nil), ks...)
+	sort.Ints(ks)
+	limit := ks[len(ks)-1]
+
+	report := &Report{Ks: ks}
+	for _, cfg := range configs {
+		result := ConfigResult{Name: cfg.Name, AtK: make([]AtK, len(ks))}
+		for i, k := range ks {
+			result.AtK[i].K = k
+		}
+
+		for _, q := range queries {
+			docs, err := cfg.Retriever.Retrieve(ctx, q.Text, limit)
+			if err != nil {
+				return nil, fmt.Errorf("config %s: query %s: %w", cfg.Name, q.ID, err)
+			}
+
+			retrieved := make([]string, len(docs))
+			for i, doc := range docs {
+				retrieved[i] = doc.ID
+			}
+
+			relevant := q.relevance()
+			qr := QueryResult{
+				QueryID:   q.ID,
+				Retrieved: retrieved,
+				RR:        ReciprocalRank(retrieved, relevant),
+				AtK:       make([]AtK, len(ks)),
+			}
+			for i, k := range ks {
+				qr.AtK[i] = AtK{
+					K:         k,
+					Recall:    RecallAtK(retrieved, relevant, k),
+					Precision: PrecisionAtK(retrieved, relevant, k),
+					NDCG:      NDCGAtK(retrieved, relevant, k),
+				}
+				result.AtK[i].Recall += qr.AtK[i].Recall
+				result.AtK[i].Precision += qr.AtK[i].Precision
+				result.AtK[i].NDCG += qr.AtK[i].NDCG
+			}
+			result.MRR += qr.RR
+			result.Queries = append(result.Queries, qr)
+		}
+
+		if n := float64(len(queries)); n > 0 {
+			result.MRR /= n
+			for i := range result.AtK {
+				result.AtK[i].Recall /= n
+				result.AtK[i].Precision /= n
+				result.AtK[i].NDCG /= n
+			}
+		}
+		report.Configs = append(report.Configs, result)
+	}
+
+	return report, nil
+}
+
+// WriteJSON writes the report, including per-query results, as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteText writes a table of the aggregated metrics, one row per configuration.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"config", "mrr"}
+	for _, k := range r.Ks {
+		header = append(header, fmt.Sprintf("recall@%d", k), fmt.Sprintf("precision@%d", k), fmt.Sprintf("ndcg@%d", k))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, c := range r.Configs {
+		row := []string{c.Name, fmt.Sprintf("%.4f", c.MRR)}
+		for _, m := range c.AtK {
+			row = append(row, fmt.Sprintf("%.4f", m.Recall), fmt.Sprintf("%.4f", m.Precision), fmt.Sprintf("%.4f", m.NDCG))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
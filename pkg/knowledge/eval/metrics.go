@@ -0,0 +1,96 @@
+package eval
+
+import (
+	"math"
+	"sort"
+)
+
+// Relevance maps relevant document IDs to their graded relevance.
+// Binary labels use a grade of 1.
+type Relevance map[string]float64
+
+// RecallAtK is the fraction of relevant documents found in the top k results.
+// Documents graded 0 are judged not relevant and do not count.
+func RecallAtK(retrieved []string, relevant Relevance, k int) float64 {
+	n := 0
+	for _, grade := range relevant {
+		if grade > 0 {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(hits(retrieved, relevant, k)) / float64(n)
+}
+
+// PrecisionAtK is the fraction of the top k results that are relevant.
+func PrecisionAtK(retrieved []string, relevant Relevance, k int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	return float64(hits(retrieved, relevant, k)) / float64(k)
+}
+
+// ReciprocalRank is 1 / rank of the first relevant result, or 0 if none is found.
+func ReciprocalRank(retrieved []string, relevant Relevance) float64 {
+	for i, id := range retrieved {
+		if relevant[id] > 0 {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// NDCGAtK is the normalized discounted cumulative gain of the top k results.
+func NDCGAtK(retrieved []string, relevant Relevance, k int) float64 {
+	var dcg float64
+	for i, id := range top(retrieved, k) {
+		dcg += gain(relevant[id], i)
+	}
+
+	ideal := idealGrades(relevant)
+	var idcg float64
+	for i, grade := range top(ideal, k) {
+		idcg += gain(grade, i)
+	}
+
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+func gain(grade float64, position int) float64 {
+	return (math.Pow(2, grade) - 1) / math.Log2(float64(position)+2)
+}
+
+// hits counts the distinct relevant documents in the top k results.
+func hits(retrieved []string, relevant Relevance, k int) int {
+	seen := make(map[string]bool)
+	for _, id := range top(retrieved, k) {
+		if relevant[id] > 0 {
+			seen[id] = true
+		}
+	}
+	return len(seen)
+}
+
+func top[T any](items []T, k int) []T {
+	if k >= 0 && len(items) > k {
+		return items[:k]
+	}
+	return items
+}
+
+// idealGrades returns the relevance grades sorted in descending order.
+func idealGrades(relevant Relevance) []float64 {
+	grades := make([]float64, 0, len(relevant))
+	for _, g := range relevant {
+		if g > 0 {
+			grades = append(grades, g)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(grades)))
+	return grades
+}
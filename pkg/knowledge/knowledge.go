@@ -2,6 +2,8 @@ package knowledge
 
 import (
 	"context"
+	"fmt"
+	"math"
 )
 
 // Document represents a piece of text with metadata.
@@ -12,6 +14,14 @@ type Document struct {
 	Score    float32                `json:"score,omitempty"` // Similarity score
 }
 
+// Key identifies a document for deduplication: its ID, or its content when it has no ID.
+func (d Document) Key() string {
+	if d.ID == "" {
+		return "content:" + d.Content
+	}
+	return "id:" + d.ID
+}
+
 // Embedder is the interface for generating embeddings.
 type Embedder interface {
 	Embed(ctx context.Context, texts []string) ([][]float32, error)
@@ -25,10 +35,89 @@ type VectorStore interface {
 	Search(ctx context.Context, query []float32, limit int) ([]Document, error)
 }
 
+// Filter restricts a search to documents whose metadata matches every key.
+// A value may be a scalar, which must equal the metadata value, or a slice, which matches any of its elements.
+type Filter map[string]interface{}
+
+// Match reports whether the document metadata satisfies the filter.
+func (f Filter) Match(doc Document) bool {
+	for key, want := range f {
+		got, ok := doc.Metadata[key]
+		if !ok {
+			return false
+		}
+		if options, ok := want.([]interface{}); ok {
+			if !matchAny(got, options) {
+				return false
+			}
+			continue
+		}
+		if !equalValues(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(got interface{}, options []interface{}) bool {
+	for _, want := range options {
+		if equalValues(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares metadata values, treating all numeric types as float64.
+func equalValues(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// FilterableStore is implemented by vector stores that can apply a Filter natively.
+type FilterableStore interface {
+	VectorStore
+	// SearchWithFilter searches for similar documents whose metadata matches the filter.
+	SearchWithFilter(ctx context.Context, query []float32, limit int, filter Filter) ([]Document, error)
+}
+
+// filterOverfetch is how many extra candidates are fetched when a store cannot filter natively.
+const filterOverfetch = 5
+
 // KnowledgeBase combines an Embedder and a VectorStore.
 type KnowledgeBase struct {
 	Embedder    Embedder
 	VectorStore VectorStore
+	// Mode selects how search hits are turned into results. Defaults to ModeDirect.
+	Mode RetrievalMode
+	// Parents holds the parent documents used by ModeParent.
+	Parents DocumentStore
 }
 
 // NewKnowledgeBase creates a new KnowledgeBase.
@@ -56,6 +145,12 @@ func (kb *KnowledgeBase) Ingest(ctx context.Context, docs []Document) error {
 
 // Retrieve finds relevant documents for a query.
 func (kb *KnowledgeBase) Retrieve(ctx context.Context, query string, limit int) ([]Document, error) {
+	return kb.RetrieveWithFilter(ctx, query, limit, nil)
+}
+
+// RetrieveWithFilter finds relevant documents for a query whose metadata matches the filter.
+// Stores that do not implement FilterableStore are over-fetched and filtered in memory.
+func (kb *KnowledgeBase) RetrieveWithFilter(ctx context.Context, query string, limit int, filter Filter) ([]Document, error) {
 	vectors, err := kb.Embedder.Embed(ctx, []string{query})
 	if err != nil {
 		return nil, err
@@ -65,5 +160,59 @@ func (kb *KnowledgeBase) Retrieve(ctx context.Context, query string, limit int)
 		return nil, nil
 	}
 
-	return kb.VectorStore.Search(ctx, vectors[0], limit)
+	if kb.Mode == ModeParent {
+		children, err := kb.search(ctx, vectors[0], limit*parentOverfetch, filter)
+		if err != nil {
+			return nil, err
+		}
+		return kb.expandParents(ctx, children, limit)
+	}
+
+	return kb.search(ctx, vectors[0], limit, filter)
+}
+
+// search queries the vector store, applying the filter natively when the store supports it.
+func (kb *KnowledgeBase) search(ctx context.Context, vector []float32, limit int, filter Filter) ([]Document, error) {
+	if len(filter) == 0 {
+		return kb.VectorStore.Search(ctx, vector, limit)
+	}
+
+	if store, ok := kb.VectorStore.(FilterableStore); ok {
+		return store.SearchWithFilter(ctx, vector, limit, filter)
+	}
+
+	candidates, err := kb.VectorStore.Search(ctx, vector, limit*filterOverfetch)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []Document
+	for _, doc := range candidates {
+		if filter.Match(doc) {
+			docs = append(docs, doc)
+			if len(docs) == limit {
+				break
+			}
+		}
+	}
+	return docs, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 if either is zero
+// or their lengths differ.
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
 }
@@ -0,0 +1,176 @@
+// Package local provides dependency-free knowledge components that run without network access.
+package local
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Embedder implements knowledge.Embedder with hashed n-gram feature vectors.
+// Word n-grams and character n-grams are hashed into a fixed number of buckets,
+// weighted by sublinear term frequency and, after Fit, inverse document frequency.
+// The output is deterministic and L2-normalized, so cosine similarity works as expected.
+type Embedder struct {
+	dimension  int
+	wordNGrams int
+	charMin    int
+	charMax    int
+	mu         sync.RWMutex
+	idf        []float32
+}
+
+// Option configures an Embedder.
+type Option func(*Embedder)
+
+// WithWordNGrams sets the largest word n-gram used as a feature. 1 uses single words only.
+func WithWordNGrams(n int) Option {
+	return func(e *Embedder) {
+		e.wordNGrams = n
+	}
+}
+
+// WithCharNGrams sets the range of character n-gram sizes. A zero max disables character features.
+func WithCharNGrams(minN, maxN int) Option {
+	return func(e *Embedder) {
+		e.charMin = minN
+		e.charMax = maxN
+	}
+}
+
+// NewEmbedder creates an Embedder producing vectors of the given dimension.
+func NewEmbedder(dimension int, opts ...Option) (*Embedder, error) {
+	if dimension <= 0 {
+		return nil, fmt.Errorf("invalid dimension: %d", dimension)
+	}
+
+	e := &Embedder{
+		dimension:  dimension,
+		wordNGrams: 2,
+		charMin:    3,
+		charMax:    4,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Fit computes inverse document frequency weights from a corpus.
+// Without Fit every feature has weight one.
+func (e *Embedder) Fit(corpus []string) {
+	df := make([]int, e.dimension)
+	for _, text := range corpus {
+		seen := make(map[int]bool)
+		for _, f := range e.features(text) {
+			bucket, _ := e.bucket(f)
+			if !seen[bucket] {
+				seen[bucket] = true
+				df[bucket]++
+			}
+		}
+	}
+
+	idf := make([]float32, e.dimension)
+	n := float64(len(corpus))
+	for i, count := range df {
+		idf[i] = float32(math.Log((1+n)/(1+float64(count))) + 1)
+	}
+
+	e.mu.Lock()
+	e.idf = idf
+	e.mu.Unlock()
+}
+
+// Embed generates embeddings for the given texts.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.RLock()
+	idf := e.idf
+	e.mu.RUnlock()
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = e.embed(text, idf)
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) embed(text string, idf []float32) []float32 {
+	counts := make(map[string]int)
+	for _, f := range e.features(text) {
+		counts[f]++
+	}
+
+	vec := make([]float32, e.dimension)
+	for f, count := range counts {
+		bucket, sign := e.bucket(f)
+		weight := float32(1 + math.Log(float64(count)))
+		if idf != nil {
+			weight *= idf[bucket]
+		}
+		vec[bucket] += sign * weight
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// features returns the word and character n-grams of text.
+func (e *Embedder) features(text string) []string {
+	words := tokenize(text)
+
+	var features []string
+	for n := 1; n <= e.wordNGrams; n++ {
+		for i := 0; i+n <= len(words); i++ {
+			features = append(features, "w:"+strings.Join(words[i:i+n], " "))
+		}
+	}
+
+	if e.charMax > 0 {
+		for _, word := range words {
+			runes := []rune("<" + word + ">")
+			for n := e.charMin; n <= e.charMax; n++ {
+				for i := 0; i+n <= len(runes); i++ {
+					features = append(features, "c:"+string(runes[i:i+n]))
+				}
+			}
+		}
+	}
+	return features
+}
+
+// bucket hashes a feature to a vector index and a sign, which keeps collisions from biasing similarity.
+func (e *Embedder) bucket(feature string) (int, float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	sign := float32(1)
+	if sum>>63 == 1 {
+		sign = -1
+	}
+	return int(sum % uint64(e.dimension)), sign
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter or digit.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,97 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/barekit/talos/pkg/knowledge"
+)
+
+// Store implements knowledge.VectorStore in memory with brute-force cosine search.
+type Store struct {
+	mu      sync.RWMutex
+	ids     []string
+	docs    map[string]knowledge.Document
+	vectors map[string][]float32
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{
+		docs:    make(map[string]knowledge.Document),
+		vectors: make(map[string][]float32),
+	}
+}
+
+// Upsert inserts or updates documents and their vectors.
+func (s *Store) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
+	if len(vectors) != len(documents) {
+		return fmt.Errorf("number of vectors and documents must match")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, doc := range documents {
+		if _, ok := s.docs[doc.ID]; !ok {
+			s.ids = append(s.ids, doc.ID)
+		}
+		doc.Score = 0
+		s.docs[doc.ID] = doc
+		s.vectors[doc.ID] = vectors[i]
+	}
+	return nil
+}
+
+// Search searches for similar documents using a query vector.
+func (s *Store) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter searches for similar documents whose metadata matches the filter.
+func (s *Store) SearchWithFilter(ctx context.Context, query []float32, limit int, filter knowledge.Filter) ([]knowledge.Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []knowledge.Document
+	for _, id := range s.ids {
+		doc := s.docs[id]
+		if !filter.Match(doc) {
+			continue
+		}
+		doc.Score = knowledge.CosineSimilarity(query, s.vectors[id])
+		results = append(results, doc)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Delete removes documents by ID. Unknown IDs are ignored.
+func (s *Store) Delete(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+		delete(s.docs, id)
+		delete(s.vectors, id)
+	}
+
+	kept := s.ids[:0]
+	for _, id := range s.ids {
+		if !remove[id] {
+			kept = append(kept, id)
+		}
+	}
+	s.ids = kept
+	return nil
+}
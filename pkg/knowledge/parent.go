@@ -0,0 +1,257 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MetadataParentID links a child chunk to its parent document.
+const MetadataParentID = "parent_id"
+
+// metadataChildren is the number of child chunks of a stored parent document. It is
+// reserved for IngestParents and removed from the parents returned by retrieval.
+const metadataChildren = "_talos_children"
+
+// RetrievalMode selects how search hits are turned into results.
+type RetrievalMode string
+
+const (
+	// ModeDirect returns the documents found by the vector search.
+	ModeDirect RetrievalMode = ""
+	// ModeParent searches small child chunks and returns their deduplicated parent documents.
+	ModeParent RetrievalMode = "parent"
+)
+
+// parentOverfetch is how many child hits are fetched per requested parent.
+const parentOverfetch = 4
+
+// DocumentStore holds full documents by ID, outside the vector store.
+type DocumentStore interface {
+	// Put inserts or replaces documents.
+	Put(ctx context.Context, docs []Document) error
+	// Get returns the documents with the given IDs in the same order. Missing IDs are skipped.
+	Get(ctx context.Context, ids []string) ([]Document, error)
+}
+
+// MemoryDocumentStore is an in-process DocumentStore.
+type MemoryDocumentStore struct {
+	mu   sync.RWMutex
+	docs map[string]Document
+}
+
+// NewMemoryDocumentStore creates an empty MemoryDocumentStore.
+func NewMemoryDocumentStore() *MemoryDocumentStore {
+	return &MemoryDocumentStore{docs: make(map[string]Document)}
+}
+
+// Put implements DocumentStore.
+func (s *MemoryDocumentStore) Put(ctx context.Context, docs []Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Get implements DocumentStore.
+func (s *MemoryDocumentStore) Get(ctx context.Context, ids []string) ([]Document, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs := make([]Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.docs[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// NewParentKnowledgeBase creates a KnowledgeBase in ModeParent.
+func NewParentKnowledgeBase(embedder Embedder, store VectorStore, parents DocumentStore) *KnowledgeBase {
+	return &KnowledgeBase{
+		Embedder:    embedder,
+		VectorStore: store,
+		Mode:        ModeParent,
+		Parents:     parents,
+	}
+}
+
+// IngestParents stores the parent documents and embeds their child chunks.
+// Children are named "{parentID}#{index}" and carry the parent metadata plus
+// MetadataParentID and the chunk offsets, so filters apply to children as to parents.
+// Whitespace-only chunks are skipped. The stored parents record their number of children
+// under a reserved metadata key, so re-ingesting a shorter parent deletes the children it no longer has.
+func (kb *KnowledgeBase) IngestParents(ctx context.Context, parents []Document, splitter Splitter) error {
+	if kb.Parents == nil {
+		return fmt.Errorf("parent ingestion requires a document store")
+	}
+	if splitter == nil {
+		return fmt.Errorf("parent ingestion requires a splitter")
+	}
+
+	ids := make([]string, len(parents))
+	for i, parent := range parents {
+		ids[i] = parent.ID
+	}
+	previous, err := kb.Parents.Get(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to load parent documents: %w", err)
+	}
+	counts := make(map[string]int, len(previous))
+	for _, parent := range previous {
+		counts[parent.ID] = childCount(parent, splitter)
+	}
+
+	var children []Document
+	var stale []string
+	stored := make([]Document, len(parents))
+	for i, parent := range parents {
+		kids := splitParent(parent, splitter)
+		children = append(children, kids...)
+		for j := len(kids); j < counts[parent.ID]; j++ {
+			stale = append(stale, childID(parent.ID, j))
+		}
+
+		metadata := make(map[string]interface{}, len(parent.Metadata)+1)
+		for k, v := range parent.Metadata {
+			metadata[k] = v
+		}
+		metadata[metadataChildren] = len(kids)
+		parent.Metadata = metadata
+		stored[i] = parent
+	}
+
+	if err := kb.Parents.Put(ctx, stored); err != nil {
+		return fmt.Errorf("failed to store parent documents: %w", err)
+	}
+	if len(children) > 0 {
+		if err := kb.Ingest(ctx, children); err != nil {
+			return err
+		}
+	}
+	if err := kb.delete(ctx, stale); err != nil {
+		return fmt.Errorf("failed to delete stale child chunks: %w", err)
+	}
+	return nil
+}
+
+// splitParent returns the child chunks of a parent document.
+func splitParent(parent Document, splitter Splitter) []Document {
+	var children []Document
+	for _, chunk := range splitter(parent.Content) {
+		if strings.TrimSpace(chunk.Text) == "" {
+			continue
+		}
+
+		metadata := make(map[string]interface{}, len(parent.Metadata)+3)
+		for k, v := range parent.Metadata {
+			if k != metadataChildren {
+				metadata[k] = v
+			}
+		}
+		metadata[MetadataParentID] = parent.ID
+		metadata[MetadataStart] = chunk.Start
+		metadata[MetadataEnd] = chunk.End
+
+		children = append(children, Document{
+			ID:       childID(parent.ID, len(children)),
+			Content:  chunk.Text,
+			Metadata: metadata,
+		})
+	}
+	return children
+}
+
+// childCount returns the number of children a stored parent was ingested with. Parents
+// stored before the count was recorded are split again.
+func childCount(parent Document, splitter Splitter) int {
+	switch n := parent.Metadata[metadataChildren].(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return len(splitter(parent.Content))
+}
+
+func childID(parentID string, index int) string {
+	return fmt.Sprintf("%s#%d", parentID, index)
+}
+
+// expandParents maps child hits to their parents, keeping the order of the best child
+// and its score. Hits without a parent are returned as they are.
+func (kb *KnowledgeBase) expandParents(ctx context.Context, children []Document, limit int) ([]Document, error) {
+	if kb.Parents == nil {
+		return nil, fmt.Errorf("parent retrieval requires a document store")
+	}
+
+	var (
+		order  []string
+		scores = make(map[string]float32)
+		direct = make(map[string]Document)
+	)
+	for _, child := range children {
+		id, ok := child.Metadata[MetadataParentID].(string)
+		if !ok || id == "" {
+			id = child.ID
+			if _, seen := direct[id]; !seen {
+				direct[id] = child
+			}
+		}
+		if _, seen := scores[id]; !seen {
+			if len(order) == limit {
+				continue
+			}
+			order = append(order, id)
+			scores[id] = child.Score
+		} else if child.Score > scores[id] {
+			scores[id] = child.Score
+		}
+	}
+
+	var parentIDs []string
+	for _, id := range order {
+		if _, ok := direct[id]; !ok {
+			parentIDs = append(parentIDs, id)
+		}
+	}
+
+	parents, err := kb.Parents.Get(ctx, parentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load parent documents: %w", err)
+	}
+	byID := make(map[string]Document, len(parents)+len(direct))
+	for _, p := range parents {
+		byID[p.ID] = p
+	}
+	for id, d := range direct {
+		byID[id] = d
+	}
+
+	docs := make([]Document, 0, len(order))
+	for _, id := range order {
+		doc, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if _, ok := doc.Metadata[metadataChildren]; ok {
+			metadata := make(map[string]interface{}, len(doc.Metadata)-1)
+			for k, v := range doc.Metadata {
+				if k != metadataChildren {
+					metadata[k] = v
+				}
+			}
+			doc.Metadata = metadata
+		}
+		doc.Score = scores[id]
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
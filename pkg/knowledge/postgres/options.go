@@ -0,0 +1,132 @@
+package postgres
+
+import "fmt"
+
+// Distance is the metric used to compare vectors.
+type Distance string
+
+const (
+	// DistanceCosine uses cosine distance (<=>). Score is 1 - distance.
+	DistanceCosine Distance = "cosine"
+	// DistanceL2 uses Euclidean distance (<->). Score is 1 / (1 + distance).
+	DistanceL2 Distance = "l2"
+	// DistanceInnerProduct uses negative inner product (<#>). Score is the inner product.
+	DistanceInnerProduct Distance = "inner_product"
+)
+
+// operator returns the pgvector distance operator.
+func (d Distance) operator() string {
+	switch d {
+	case DistanceL2:
+		return "<->"
+	case DistanceInnerProduct:
+		return "<#>"
+	default:
+		return "<=>"
+	}
+}
+
+// opClass returns the pgvector operator class used by ANN indexes.
+func (d Distance) opClass() string {
+	switch d {
+	case DistanceL2:
+		return "vector_l2_ops"
+	case DistanceInnerProduct:
+		return "vector_ip_ops"
+	default:
+		return "vector_cosine_ops"
+	}
+}
+
+// Score converts a distance returned by the metric's operator into a similarity score where higher is better.
+func (d Distance) Score(distance float64) float32 {
+	switch d {
+	case DistanceL2:
+		return float32(1 / (1 + distance))
+	case DistanceInnerProduct:
+		return float32(-distance)
+	default:
+		return float32(1 - distance)
+	}
+}
+
+func (d Distance) validate() error {
+	switch d {
+	case DistanceCosine, DistanceL2, DistanceInnerProduct:
+		return nil
+	default:
+		return fmt.Errorf("unsupported distance metric: %s", d)
+	}
+}
+
+// HNSWParams configures an HNSW index. Zero values use the pgvector defaults.
+type HNSWParams struct {
+	M              int
+	EfConstruction int
+	// EfSearch sets hnsw.ef_search for each search.
+	EfSearch int
+}
+
+// IVFFlatParams configures an IVFFlat index. Zero values use the pgvector defaults.
+type IVFFlatParams struct {
+	Lists int
+	// Probes sets ivfflat.probes for each search.
+	Probes int
+}
+
+type config struct {
+	table     string
+	dimension int
+	distance  Distance
+	hnsw      *HNSWParams
+	ivfflat   *IVFFlatParams
+}
+
+func defaultConfig() config {
+	return config{
+		table:     "documents",
+		dimension: 1536,
+		distance:  DistanceCosine,
+	}
+}
+
+// Option configures a PostgresStore.
+type Option func(*config)
+
+// WithTableName sets the table that holds the documents. Use one table per knowledge base.
+func WithTableName(name string) Option {
+	return func(c *config) {
+		c.table = name
+	}
+}
+
+// WithDimension sets the vector dimension. It must match the embedding model.
+func WithDimension(dimension int) Option {
+	return func(c *config) {
+		c.dimension = dimension
+	}
+}
+
+// WithDistance sets the distance metric used for search and indexing.
+func WithDistance(distance Distance) Option {
+	return func(c *config) {
+		c.distance = distance
+	}
+}
+
+// WithHNSWIndex creates an HNSW index on the embedding column.
+func WithHNSWIndex(params HNSWParams) Option {
+	return func(c *config) {
+		c.hnsw = &params
+		c.ivfflat = nil
+	}
+}
+
+// WithIVFFlatIndex creates an IVFFlat index on the embedding column.
+// IVFFlat indexes should be created after the table holds representative data.
+func WithIVFFlatIndex(params IVFFlatParams) Option {
+	return func(c *config) {
+		c.ivfflat = &params
+		c.hnsw = nil
+	}
+}
@@ -2,7 +2,11 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/barekit/talos/pkg/knowledge"
 	"github.com/pgvector/pgvector-go"
@@ -13,40 +17,111 @@ import (
 
 // PostgresStore implements knowledge.VectorStore using pgvector.
 type PostgresStore struct {
-	db *gorm.DB
+	db  *gorm.DB
+	cfg config
 }
 
 // DocumentModel represents the database schema for a document.
+// The table is set with WithTableName and the embedding column is created with the configured dimension.
 type DocumentModel struct {
 	ID        string `gorm:"primaryKey"`
 	Content   string
-	Metadata  []byte          `gorm:"type:jsonb"`        // Store metadata as JSONB
-	Embedding pgvector.Vector `gorm:"type:vector(1536)"` // Adjust dimension as needed
+	Metadata  []byte          `gorm:"type:jsonb"` // Store metadata as JSONB
+	Embedding pgvector.Vector `gorm:"type:vector"`
 }
 
-// TableName overrides the table name.
-func (DocumentModel) TableName() string {
-	return "documents"
-}
+// identifier matches table names that are safe to interpolate into SQL.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
 // New creates a new PostgresStore.
-func New(dsn string) (*PostgresStore, error) {
+func New(dsn string, opts ...Option) (*PostgresStore, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	return NewWithDB(db, opts...)
+}
+
+// NewWithDB creates a new PostgresStore on an existing connection.
+// Several stores with different tables can share one connection.
+func NewWithDB(db *gorm.DB, opts ...Option) (*PostgresStore, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !identifier.MatchString(cfg.table) {
+		return nil, fmt.Errorf("invalid table name: %q", cfg.table)
+	}
+	if cfg.dimension <= 0 {
+		return nil, fmt.Errorf("invalid vector dimension: %d", cfg.dimension)
+	}
+	if err := cfg.distance.validate(); err != nil {
+		return nil, err
+	}
 
 	// Enable pgvector extension
 	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
 		return nil, fmt.Errorf("failed to enable pgvector extension: %w", err)
 	}
 
-	// AutoMigrate
-	if err := db.AutoMigrate(&DocumentModel{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	s := &PostgresStore{db: db, cfg: cfg}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate creates the document table and the configured ANN index.
+func (s *PostgresStore) migrate() error {
+	createTable := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %q (
+		id text PRIMARY KEY,
+		content text,
+		metadata jsonb,
+		embedding vector(%d)
+	)`, s.cfg.table, s.cfg.dimension)
+	if err := s.db.Exec(createTable).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	var index string
+	switch {
+	case s.cfg.hnsw != nil:
+		index = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %q ON %q USING hnsw (embedding %s)%s`,
+			s.cfg.table+"_embedding_hnsw_idx", s.cfg.table, s.cfg.distance.opClass(),
+			withParams(map[string]int{"m": s.cfg.hnsw.M, "ef_construction": s.cfg.hnsw.EfConstruction}))
+	case s.cfg.ivfflat != nil:
+		index = fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %q ON %q USING ivfflat (embedding %s)%s`,
+			s.cfg.table+"_embedding_ivfflat_idx", s.cfg.table, s.cfg.distance.opClass(),
+			withParams(map[string]int{"lists": s.cfg.ivfflat.Lists}))
+	default:
+		return nil
 	}
 
-	return &PostgresStore{db: db}, nil
+	if err := s.db.Exec(index).Error; err != nil {
+		return fmt.Errorf("failed to create vector index: %w", err)
+	}
+	return nil
+}
+
+// withParams renders the WITH clause of an index, skipping unset parameters.
+func withParams(params map[string]int) string {
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if v > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s = %d", k, params[k])
+	}
+	return " WITH (" + strings.Join(parts, ", ") + ")"
 }
 
 func (s *PostgresStore) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
@@ -55,11 +130,12 @@ func (s *PostgresStore) Upsert(ctx context.Context, vectors [][]float32, documen
 	}
 
 	// Use a transaction
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		for i, doc := range documents {
-			// Convert metadata to JSON bytes (simplified)
-			// In a real app, use json.Marshal
-			metadataJSON := []byte("{}")
+			metadataJSON, err := marshalMetadata(doc.Metadata)
+			if err != nil {
+				return fmt.Errorf("failed to marshal metadata for document %s: %w", doc.ID, err)
+			}
 
 			model := DocumentModel{
 				ID:        doc.ID,
@@ -69,7 +145,7 @@ func (s *PostgresStore) Upsert(ctx context.Context, vectors [][]float32, documen
 			}
 
 			// Upsert
-			if err := tx.Clauses(clause.OnConflict{
+			if err := tx.Table(s.cfg.table).Clauses(clause.OnConflict{
 				Columns:   []clause.Column{{Name: "id"}},
 				DoUpdates: clause.AssignmentColumns([]string{"content", "metadata", "embedding"}),
 			}).Create(&model).Error; err != nil {
@@ -80,30 +156,126 @@ func (s *PostgresStore) Upsert(ctx context.Context, vectors [][]float32, documen
 	})
 }
 
+// Delete removes documents by ID. Unknown IDs are ignored.
+func (s *PostgresStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Table(s.cfg.table).Where("id IN ?", ids).Delete(&DocumentModel{}).Error
+}
+
+// searchResult is a document row with its distance to the query vector.
+type searchResult struct {
+	DocumentModel
+	Distance float64
+}
+
 func (s *PostgresStore) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
-	var models []DocumentModel
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter searches for similar documents whose metadata matches the filter.
+// Scalar filter values use JSONB containment; list values match any of their elements.
+func (s *PostgresStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter knowledge.Filter) ([]knowledge.Document, error) {
+	var results []searchResult
 
-	// Cosine distance: 1 - (A . B) / (|A| * |B|)
-	// pgvector operator for cosine distance is <=>
-	// We order by distance ascending
+	vec := pgvector.NewVector(query)
+	distance := fmt.Sprintf("embedding %s ?", s.cfg.distance.operator())
+
+	var conds []clause.Expression
+	for key, value := range filter {
+		expr, err := metadataCondition(key, value)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, expr)
+	}
 
-	err := s.db.WithContext(ctx).
-		Order(clause.Expr{SQL: "embedding <=> ?", Vars: []interface{}{pgvector.NewVector(query)}}).
-		Limit(limit).
-		Find(&models).Error
+	search := func(tx *gorm.DB) error {
+		db := tx.Table(s.cfg.table).Select("*, "+distance+" AS distance", vec)
+		for _, cond := range conds {
+			db = db.Where(cond)
+		}
+		return db.Order("distance").Limit(limit).Find(&results).Error
+	}
 
+	var err error
+	if param := s.searchParam(); param != "" {
+		// SET LOCAL only lasts until the end of the transaction.
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(param).Error; err != nil {
+				return err
+			}
+			return search(tx)
+		})
+	} else {
+		err = search(s.db.WithContext(ctx))
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	docs := make([]knowledge.Document, len(models))
-	for i, m := range models {
+	docs := make([]knowledge.Document, len(results))
+	for i, r := range results {
+		metadata, err := unmarshalMetadata(r.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata for document %s: %w", r.ID, err)
+		}
+
 		docs[i] = knowledge.Document{
-			ID:      m.ID,
-			Content: m.Content,
-			// Metadata: unmarshal m.Metadata
+			ID:       r.ID,
+			Content:  r.Content,
+			Metadata: metadata,
+			Score:    s.cfg.distance.Score(r.Distance),
 		}
 	}
 
 	return docs, nil
 }
+
+// searchParam returns the statement applying per-query index parameters, or "" if none are set.
+func (s *PostgresStore) searchParam() string {
+	if s.cfg.hnsw != nil && s.cfg.hnsw.EfSearch > 0 {
+		return fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", s.cfg.hnsw.EfSearch)
+	}
+	if s.cfg.ivfflat != nil && s.cfg.ivfflat.Probes > 0 {
+		return fmt.Sprintf("SET LOCAL ivfflat.probes = %d", s.cfg.ivfflat.Probes)
+	}
+	return ""
+}
+
+// metadataCondition builds a JSONB containment condition for a single filter key.
+func metadataCondition(key string, value interface{}) (clause.Expression, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	var exprs []clause.Expression
+	for _, v := range values {
+		b, err := json.Marshal(map[string]interface{}{key: v})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal filter for key %s: %w", key, err)
+		}
+		exprs = append(exprs, clause.Expr{SQL: "metadata @> ?::jsonb", Vars: []interface{}{string(b)}})
+	}
+	return clause.Or(exprs...), nil
+}
+
+func marshalMetadata(metadata map[string]interface{}) ([]byte, error) {
+	if len(metadata) == 0 {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(metadata)
+}
+
+func unmarshalMetadata(data []byte) (map[string]interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
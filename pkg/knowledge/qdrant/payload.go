@@ -0,0 +1,167 @@
+package qdrant
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// ToPayload converts a document into the Qdrant payload stored with its point.
+// Metadata is normalized through JSON first, so any JSON-serializable value round-trips.
+// Metadata keys must not use the reserved PayloadContent and PayloadDocumentID keys.
+func ToPayload(doc knowledge.Document) (map[string]*qdrant.Value, error) {
+	payload := make(map[string]*qdrant.Value, len(doc.Metadata)+2)
+	if len(doc.Metadata) > 0 {
+		for _, key := range []string{PayloadContent, PayloadDocumentID} {
+			if _, ok := doc.Metadata[key]; ok {
+				return nil, fmt.Errorf("metadata key %q is reserved", key)
+			}
+		}
+
+		normalized, err := normalize(doc.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = qdrant.TryValueMap(normalized.(map[string]interface{})); err != nil {
+			return nil, err
+		}
+	}
+	payload[PayloadContent] = qdrant.NewValueString(doc.Content)
+	payload[PayloadDocumentID] = qdrant.NewValueString(doc.ID)
+	return payload, nil
+}
+
+// FromPayload converts a stored payload back into a document without score.
+// The ID is empty for points not written by QdrantStore.
+func FromPayload(payload map[string]*qdrant.Value) knowledge.Document {
+	doc := knowledge.Document{Metadata: make(map[string]interface{})}
+	for k, v := range payload {
+		switch k {
+		case PayloadContent:
+			doc.Content = v.GetStringValue()
+		case PayloadDocumentID:
+			doc.ID = v.GetStringValue()
+		default:
+			doc.Metadata[k] = fromValue(v)
+		}
+	}
+	return doc
+}
+
+// normalize converts v into the JSON data model, keeping integers as int64.
+func normalize(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return convertNumbers(out), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = convertNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// fromValue converts a Qdrant payload value back into a Go value.
+func fromValue(v *qdrant.Value) interface{} {
+	switch kind := v.GetKind().(type) {
+	case *qdrant.Value_BoolValue:
+		return kind.BoolValue
+	case *qdrant.Value_IntegerValue:
+		return kind.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return kind.DoubleValue
+	case *qdrant.Value_StringValue:
+		return kind.StringValue
+	case *qdrant.Value_StructValue:
+		fields := make(map[string]interface{}, len(kind.StructValue.GetFields()))
+		for k, field := range kind.StructValue.GetFields() {
+			fields[k] = fromValue(field)
+		}
+		return fields
+	case *qdrant.Value_ListValue:
+		values := make([]interface{}, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			values[i] = fromValue(item)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// ToFilter converts a knowledge.Filter into a Qdrant filter requiring every key to match.
+// A list value matches any of its items; floats match exactly.
+func ToFilter(filter knowledge.Filter) (*qdrant.Filter, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+
+	var must []*qdrant.Condition
+	for key, value := range filter {
+		normalized, err := normalize(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert filter for key %s: %w", key, err)
+		}
+
+		cond, err := toCondition(key, normalized)
+		if err != nil {
+			return nil, err
+		}
+		must = append(must, cond)
+	}
+	return &qdrant.Filter{Must: must}, nil
+}
+
+func toCondition(key string, value interface{}) (*qdrant.Condition, error) {
+	switch v := value.(type) {
+	case string:
+		return qdrant.NewMatchKeyword(key, v), nil
+	case bool:
+		return qdrant.NewMatchBool(key, v), nil
+	case int64:
+		return qdrant.NewMatchInt(key, v), nil
+	case float64:
+		return qdrant.NewRange(key, &qdrant.Range{Gte: &v, Lte: &v}), nil
+	case []interface{}:
+		var should []*qdrant.Condition
+		for _, item := range v {
+			cond, err := toCondition(key, item)
+			if err != nil {
+				return nil, err
+			}
+			should = append(should, cond)
+		}
+		return qdrant.NewFilterAsCondition(&qdrant.Filter{Should: should}), nil
+	default:
+		return nil, fmt.Errorf("unsupported filter value for key %s: %T", key, value)
+	}
+}
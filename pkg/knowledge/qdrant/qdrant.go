@@ -2,12 +2,23 @@ package qdrant
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 
 	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/google/uuid"
 	"github.com/qdrant/go-client/qdrant"
 )
 
+// Payload keys reserved by the store.
+const (
+	PayloadContent    = "content"
+	PayloadDocumentID = "document_id"
+)
+
+// idNamespace is the UUIDv5 namespace used to map document IDs to point IDs.
+var idNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/barekit/talos/knowledge/qdrant"))
+
 // QdrantStore implements knowledge.VectorStore using Qdrant.
 type QdrantStore struct {
 	client         *qdrant.Client
@@ -15,29 +26,63 @@ type QdrantStore struct {
 	vectorSize     uint64
 }
 
+// Option configures the Qdrant client connection.
+type Option func(*qdrant.Config)
+
+// WithAPIKey authenticates with the given API key.
+func WithAPIKey(apiKey string) Option {
+	return func(c *qdrant.Config) {
+		c.APIKey = apiKey
+	}
+}
+
+// WithTLS enables TLS. A nil config uses the client defaults.
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(c *qdrant.Config) {
+		c.UseTLS = true
+		c.TLSConfig = tlsConfig
+	}
+}
+
 // New creates a new QdrantStore.
-func New(host string, port int, collectionName string, vectorSize uint64) (*QdrantStore, error) {
-	client, err := qdrant.NewClient(&qdrant.Config{
+func New(host string, port int, collectionName string, vectorSize uint64, opts ...Option) (*QdrantStore, error) {
+	cfg := &qdrant.Config{
 		Host: host,
 		Port: port,
-	})
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	client, err := qdrant.NewClient(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
+	return NewWithClient(context.Background(), client, collectionName, vectorSize)
+}
+
+// NewWithClient creates a new QdrantStore on an existing client, creating the collection if needed.
+func NewWithClient(ctx context.Context, client *qdrant.Client, collectionName string, vectorSize uint64) (*QdrantStore, error) {
 	store := &QdrantStore{
 		client:         client,
 		collectionName: collectionName,
 		vectorSize:     vectorSize,
 	}
 
-	if err := store.initCollection(context.Background()); err != nil {
+	if err := store.initCollection(ctx); err != nil {
 		return nil, err
 	}
 
 	return store, nil
 }
 
+// Collection returns a store for another named collection on the same connection.
+// Knowledge bases with different embedding models can each use their own collection.
+func (s *QdrantStore) Collection(ctx context.Context, name string, vectorSize uint64) (*QdrantStore, error) {
+	return NewWithClient(ctx, s.client, name, vectorSize)
+}
+
 func (s *QdrantStore) initCollection(ctx context.Context) error {
 	// Check if collection exists
 	exists, err := s.client.CollectionExists(ctx, s.collectionName)
@@ -61,6 +106,15 @@ func (s *QdrantStore) initCollection(ctx context.Context) error {
 	return nil
 }
 
+// PointID maps a document ID to a Qdrant point ID.
+// UUIDs are used as-is; any other ID is mapped deterministically to a UUIDv5.
+func PointID(documentID string) string {
+	if id, err := uuid.Parse(documentID); err == nil {
+		return id.String()
+	}
+	return uuid.NewSHA1(idNamespace, []byte(documentID)).String()
+}
+
 func (s *QdrantStore) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
 	if len(vectors) != len(documents) {
 		return fmt.Errorf("number of vectors and documents must match")
@@ -68,18 +122,13 @@ func (s *QdrantStore) Upsert(ctx context.Context, vectors [][]float32, documents
 
 	points := make([]*qdrant.PointStruct, len(vectors))
 	for i, doc := range documents {
-		// Convert metadata to map[string]*qdrant.Value
-		payload := make(map[string]*qdrant.Value)
-		payload["content"] = qdrant.NewValueString(doc.Content)
-		for k, v := range doc.Metadata {
-			// Simple conversion for strings, can be expanded
-			if strVal, ok := v.(string); ok {
-				payload[k] = qdrant.NewValueString(strVal)
-			}
+		payload, err := ToPayload(doc)
+		if err != nil {
+			return fmt.Errorf("failed to convert metadata for document %s: %w", doc.ID, err)
 		}
 
 		points[i] = &qdrant.PointStruct{
-			Id:      qdrant.NewIDUUID(doc.ID),
+			Id:      qdrant.NewIDUUID(PointID(doc.ID)),
 			Vectors: qdrant.NewVectors(vectors[i]...),
 			Payload: payload,
 		}
@@ -94,11 +143,42 @@ func (s *QdrantStore) Upsert(ctx context.Context, vectors [][]float32, documents
 	return err
 }
 
+// Delete removes documents by ID. Unknown IDs are ignored.
+func (s *QdrantStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	pointIDs := make([]*qdrant.PointId, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = qdrant.NewIDUUID(PointID(id))
+	}
+
+	wait := true
+	_, err := s.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: s.collectionName,
+		Points:         qdrant.NewPointsSelectorIDs(pointIDs),
+		Wait:           &wait,
+	})
+	return err
+}
+
 func (s *QdrantStore) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter searches for similar documents whose payload matches the filter.
+func (s *QdrantStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter knowledge.Filter) ([]knowledge.Document, error) {
+	qfilter, err := ToFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
 	limit64 := uint64(limit)
 	res, err := s.client.Query(ctx, &qdrant.QueryPoints{
 		CollectionName: s.collectionName,
 		Query:          qdrant.NewQuery(query...),
+		Filter:         qfilter,
 		Limit:          &limit64,
 		WithPayload:    qdrant.NewWithPayload(true),
 	})
@@ -108,24 +188,12 @@ func (s *QdrantStore) Search(ctx context.Context, query []float32, limit int) ([
 
 	docs := make([]knowledge.Document, len(res))
 	for i, hit := range res {
-		content := ""
-		if c, ok := hit.Payload["content"]; ok {
-			content = c.GetStringValue()
-		}
-
-		metadata := make(map[string]interface{})
-		for k, v := range hit.Payload {
-			if k != "content" {
-				metadata[k] = v.GetStringValue() // Simplified
-			}
-		}
-
-		docs[i] = knowledge.Document{
-			ID:       hit.Id.GetUuid(),
-			Content:  content,
-			Metadata: metadata,
-			Score:    hit.Score,
+		doc := FromPayload(hit.Payload)
+		if _, ok := hit.Payload[PayloadDocumentID]; !ok {
+			doc.ID = hit.Id.GetUuid()
 		}
+		doc.Score = hit.Score
+		docs[i] = doc
 	}
 
 	return docs, nil
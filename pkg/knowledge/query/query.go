@@ -0,0 +1,151 @@
+// Package query provides query transformations applied before knowledge base retrieval.
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/llm"
+)
+
+// Transformer turns a user query into one or more retrieval queries.
+type Transformer interface {
+	Transform(ctx context.Context, query string, history []llm.Message) ([]string, error)
+}
+
+// TransformerFunc adapts a function to the Transformer interface.
+type TransformerFunc func(ctx context.Context, query string, history []llm.Message) ([]string, error)
+
+// Transform calls f.
+func (f TransformerFunc) Transform(ctx context.Context, query string, history []llm.Message) ([]string, error) {
+	return f(ctx, query, history)
+}
+
+// Chain applies transformers in order, feeding every output query of one into the next.
+// Duplicate queries are dropped.
+func Chain(transformers ...Transformer) Transformer {
+	return TransformerFunc(func(ctx context.Context, query string, history []llm.Message) ([]string, error) {
+		queries := []string{query}
+		for _, t := range transformers {
+			var next []string
+			for _, q := range queries {
+				out, err := t.Transform(ctx, q, history)
+				if err != nil {
+					return nil, err
+				}
+				next = append(next, out...)
+			}
+			queries = dedupe(next)
+		}
+		return queries, nil
+	})
+}
+
+// Retrieve transforms the query, retrieves documents for every resulting query
+// and fuses the result lists with reciprocal rank fusion.
+func Retrieve(ctx context.Context, kb *knowledge.KnowledgeBase, t Transformer, query string, history []llm.Message, limit int) ([]knowledge.Document, error) {
+	queries, err := t.Transform(ctx, query, history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform query: %w", err)
+	}
+	if len(queries) == 0 {
+		queries = []string{query}
+	}
+	return RetrieveQueries(ctx, kb, queries, limit)
+}
+
+// RetrieveQueries retrieves documents for every query and fuses the result lists
+// with reciprocal rank fusion.
+func RetrieveQueries(ctx context.Context, kb *knowledge.KnowledgeBase, queries []string, limit int) ([]knowledge.Document, error) {
+	lists := make([][]knowledge.Document, 0, len(queries))
+	for _, q := range queries {
+		docs, err := kb.Retrieve(ctx, q, limit)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, docs)
+	}
+
+	if len(lists) == 1 {
+		return lists[0], nil
+	}
+	return Fuse(lists, limit), nil
+}
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion.
+const rrfK = 60
+
+// Fuse merges ranked document lists with reciprocal rank fusion and returns at most limit documents.
+// Each document keeps its best similarity score from the input lists. Documents without
+// an ID are told apart by their content, as in knowledge.Recorder.
+func Fuse(lists [][]knowledge.Document, limit int) []knowledge.Document {
+	type fused struct {
+		doc   knowledge.Document
+		score float64
+	}
+
+	byKey := make(map[string]*fused)
+	var order []string
+	for _, docs := range lists {
+		for rank, doc := range docs {
+			key := doc.Key()
+			f, ok := byKey[key]
+			if !ok {
+				f = &fused{doc: doc}
+				byKey[key] = f
+				order = append(order, key)
+			}
+			f.score += 1.0 / float64(rrfK+rank+1)
+			if doc.Score > f.doc.Score {
+				f.doc.Score = doc.Score
+			}
+		}
+	}
+
+	results := make([]*fused, len(order))
+	for i, key := range order {
+		results[i] = byKey[key]
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	docs := make([]knowledge.Document, len(results))
+	for i, f := range results {
+		docs[i] = f.doc
+	}
+	return docs
+}
+
+// complete sends a single system/user exchange to the provider and returns the trimmed answer.
+func complete(ctx context.Context, provider llm.Provider, system, user string) (string, error) {
+	resp, err := provider.Chat(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: system},
+		{Role: llm.RoleUser, Content: user},
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+func dedupe(queries []string) []string {
+	seen := make(map[string]bool, len(queries))
+	result := queries[:0]
+	for _, q := range queries {
+		q = strings.TrimSpace(q)
+		if q == "" || seen[q] {
+			continue
+		}
+		seen[q] = true
+		result = append(result, q)
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/barekit/talos/pkg/llm"
+)
+
+const rewritePrompt = `You rewrite follow-up questions into standalone search queries.
+Use the conversation to resolve pronouns and references such as "it" or "the second one".
+Reply with the search query only, without quotes or explanations.`
+
+const multiQueryPrompt = `You generate alternative search queries for a document retrieval system.
+Write %d different phrasings of the user's question that could match relevant documents.
+Reply with one query per line, without numbering or explanations.`
+
+const hydePrompt = `Write a short passage that answers the user's question as it might appear in reference documentation.
+Reply with the passage only.`
+
+// listMarker matches bullets and numbering the model may put in front of each query.
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// Rewriter rewrites a query into a standalone query using the conversation history.
+type Rewriter struct {
+	provider llm.Provider
+	// MaxHistory is the number of most recent messages passed to the model.
+	MaxHistory int
+}
+
+// NewRewriter creates a conversation-aware Rewriter.
+func NewRewriter(provider llm.Provider) *Rewriter {
+	return &Rewriter{
+		provider:   provider,
+		MaxHistory: 6,
+	}
+}
+
+// Rewrite returns a standalone version of the query.
+// Without any user or assistant history the query is returned unchanged.
+func (r *Rewriter) Rewrite(ctx context.Context, query string, history []llm.Message) (string, error) {
+	transcript := formatHistory(history, r.MaxHistory)
+	if transcript == "" {
+		return query, nil
+	}
+
+	user := fmt.Sprintf("Conversation:\n%s\nFollow-up question: %s", transcript, query)
+	rewritten, err := complete(ctx, r.provider, rewritePrompt, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite query: %w", err)
+	}
+	if rewritten == "" {
+		return query, nil
+	}
+	return rewritten, nil
+}
+
+// Transform implements Transformer.
+func (r *Rewriter) Transform(ctx context.Context, query string, history []llm.Message) ([]string, error) {
+	rewritten, err := r.Rewrite(ctx, query, history)
+	if err != nil {
+		return nil, err
+	}
+	return []string{rewritten}, nil
+}
+
+// MultiQuery expands a query into several phrasings whose results are fused.
+type MultiQuery struct {
+	provider llm.Provider
+	// N is the number of alternative queries to generate.
+	N int
+	// IncludeOriginal keeps the original query in the output.
+	IncludeOriginal bool
+}
+
+// NewMultiQuery creates a MultiQuery generating n alternatives in addition to the original query.
+func NewMultiQuery(provider llm.Provider, n int) *MultiQuery {
+	return &MultiQuery{
+		provider:        provider,
+		N:               n,
+		IncludeOriginal: true,
+	}
+}
+
+// Transform implements Transformer.
+func (m *MultiQuery) Transform(ctx context.Context, query string, history []llm.Message) ([]string, error) {
+	answer, err := complete(ctx, m.provider, fmt.Sprintf(multiQueryPrompt, m.N), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand query: %w", err)
+	}
+
+	var alternatives []string
+	for _, line := range strings.Split(answer, "\n") {
+		line = listMarker.ReplaceAllString(line, "")
+		if line = strings.TrimSpace(line); line != "" {
+			alternatives = append(alternatives, line)
+		}
+	}
+	if m.N > 0 && len(alternatives) > m.N {
+		alternatives = alternatives[:m.N]
+	}
+
+	var queries []string
+	if m.IncludeOriginal {
+		queries = append(queries, query)
+	}
+	return dedupe(append(queries, alternatives...)), nil
+}
+
+// HyDE implements hypothetical document embedding: it retrieves with a generated
+// answer passage, which usually sits closer to relevant documents than the question does.
+type HyDE struct {
+	provider llm.Provider
+	// IncludeOriginal also retrieves with the original query.
+	IncludeOriginal bool
+}
+
+// NewHyDE creates a HyDE transformer.
+func NewHyDE(provider llm.Provider) *HyDE {
+	return &HyDE{provider: provider}
+}
+
+// Transform implements Transformer.
+func (h *HyDE) Transform(ctx context.Context, query string, history []llm.Message) ([]string, error) {
+	passage, err := complete(ctx, h.provider, hydePrompt, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate hypothetical document: %w", err)
+	}
+
+	var queries []string
+	if h.IncludeOriginal || passage == "" {
+		queries = append(queries, query)
+	}
+	if passage != "" {
+		queries = append(queries, passage)
+	}
+	return queries, nil
+}
+
+// formatHistory renders the last limit user and assistant messages as a transcript.
+func formatHistory(history []llm.Message, limit int) string {
+	var lines []string
+	for i := len(history) - 1; i >= 0 && (limit <= 0 || len(lines) < limit); i-- {
+		msg := history[i]
+		if msg.Role != llm.RoleUser && msg.Role != llm.RoleAssistant {
+			continue
+		}
+		if msg.Content == "" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", msg.Role, msg.Content))
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+package knowledge
+
+import (
+	"context"
+	"sync"
+)
+
+// Well-known metadata keys used for citations.
+const (
+	MetadataSource = "source"
+	MetadataURL    = "url"
+	MetadataStart  = "start"
+	MetadataEnd    = "end"
+)
+
+// Recorder collects the documents handed to a model during a run, in retrieval order.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu   sync.Mutex
+	docs []Document
+	seen map[string]bool
+}
+
+// NewRecorder creates an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{seen: make(map[string]bool)}
+}
+
+// Record adds documents to the recorder. Documents already recorded are skipped;
+// documents without an ID are told apart by their content.
+func (r *Recorder) Record(docs ...Document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, doc := range docs {
+		key := doc.Key()
+		if r.seen[key] {
+			continue
+		}
+		r.seen[key] = true
+		r.docs = append(r.docs, doc)
+	}
+}
+
+// Documents returns a copy of the recorded documents.
+func (r *Recorder) Documents() []Document {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]Document, len(r.docs))
+	copy(result, r.docs)
+	return result
+}
+
+type recorderKey struct{}
+
+// ContextWithRecorder returns a context carrying the recorder.
+func ContextWithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// RecordRetrieved adds documents to the recorder carried by ctx, if any.
+func RecordRetrieved(ctx context.Context, docs ...Document) {
+	if r, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		r.Record(docs...)
+	}
+}
@@ -0,0 +1,130 @@
+package redis
+
+import "fmt"
+
+// Distance is the metric used to compare vectors.
+type Distance string
+
+const (
+	// DistanceCosine uses cosine distance. Score is 1 - distance.
+	DistanceCosine Distance = "COSINE"
+	// DistanceL2 uses squared Euclidean distance. Score is 1 / (1 + distance).
+	DistanceL2 Distance = "L2"
+	// DistanceInnerProduct uses 1 - inner product. Score is the inner product.
+	DistanceInnerProduct Distance = "IP"
+)
+
+// score converts a distance into a similarity score where higher is better.
+func (d Distance) score(distance float64) float32 {
+	if d == DistanceL2 {
+		return float32(1 / (1 + distance))
+	}
+	return float32(1 - distance)
+}
+
+func (d Distance) validate() error {
+	switch d {
+	case DistanceCosine, DistanceL2, DistanceInnerProduct:
+		return nil
+	default:
+		return fmt.Errorf("unsupported distance metric: %s", d)
+	}
+}
+
+// Algorithm is the vector index type.
+type Algorithm string
+
+const (
+	// AlgorithmHNSW builds an approximate HNSW graph.
+	AlgorithmHNSW Algorithm = "HNSW"
+	// AlgorithmFlat searches exhaustively. Suited to small corpora.
+	AlgorithmFlat Algorithm = "FLAT"
+)
+
+// HNSWParams configures an HNSW index. Zero values use the RediSearch defaults.
+type HNSWParams struct {
+	M              int
+	EfConstruction int
+	EfRuntime      int
+}
+
+type config struct {
+	index     string
+	prefix    string
+	dimension int
+	distance  Distance
+	algorithm Algorithm
+	hnsw      HNSWParams
+	tags      []string
+	numerics  []string
+}
+
+func defaultConfig() config {
+	return config{
+		index:     "talos_documents",
+		dimension: 1536,
+		distance:  DistanceCosine,
+		algorithm: AlgorithmHNSW,
+	}
+}
+
+// Option configures a RedisStore.
+type Option func(*config)
+
+// WithIndexName sets the RediSearch index name. Use one index per knowledge base.
+func WithIndexName(name string) Option {
+	return func(c *config) {
+		c.index = name
+	}
+}
+
+// WithPrefix sets the key prefix of document hashes. Defaults to "{index}:".
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithDimension sets the vector dimension. It must match the embedding model.
+func WithDimension(dimension int) Option {
+	return func(c *config) {
+		c.dimension = dimension
+	}
+}
+
+// WithDistance sets the distance metric used for indexing and search.
+func WithDistance(distance Distance) Option {
+	return func(c *config) {
+		c.distance = distance
+	}
+}
+
+// WithHNSWIndex uses an HNSW vector index. This is the default.
+func WithHNSWIndex(params HNSWParams) Option {
+	return func(c *config) {
+		c.algorithm = AlgorithmHNSW
+		c.hnsw = params
+	}
+}
+
+// WithFlatIndex uses a FLAT (exhaustive) vector index.
+func WithFlatIndex() Option {
+	return func(c *config) {
+		c.algorithm = AlgorithmFlat
+	}
+}
+
+// WithTagFields indexes metadata keys as TAG fields for exact-match filtering.
+// List values are indexed as multiple tags. Tags cannot contain commas.
+func WithTagFields(keys ...string) Option {
+	return func(c *config) {
+		c.tags = append(c.tags, keys...)
+	}
+}
+
+// WithNumericFields indexes metadata keys as NUMERIC fields for filtering.
+func WithNumericFields(keys ...string) Option {
+	return func(c *config) {
+		c.numerics = append(c.numerics, keys...)
+	}
+}
@@ -0,0 +1,460 @@
+package redis
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/redis/go-redis/v9"
+)
+
+// Hash fields written for every document.
+const (
+	fieldID        = "id"
+	fieldContent   = "content"
+	fieldMetadata  = "metadata"
+	fieldEmbedding = "embedding"
+	fieldDistance  = "distance"
+	// metadataPrefix prefixes hash fields holding indexed metadata values.
+	metadataPrefix = "meta_"
+)
+
+// identifier matches metadata keys that can be used as index attributes.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RedisStore implements knowledge.VectorStore using RediSearch vector indexes.
+// Documents are stored as hashes under the configured prefix.
+type RedisStore struct {
+	client   redis.UniversalClient
+	cfg      config
+	tags     map[string]bool
+	numerics map[string]bool
+}
+
+// New creates a RedisStore on an existing client, creating the index if needed.
+// An index whose schema no longer matches the options is dropped and recreated;
+// RediSearch then re-indexes the existing documents.
+//
+// go-redis only parses search replies over RESP2 unless UnstableResp3 is set,
+// so the client needs Protocol: 2 or UnstableResp3: true.
+func New(ctx context.Context, client redis.UniversalClient, opts ...Option) (*RedisStore, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := cfg.distance.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.dimension <= 0 {
+		return nil, fmt.Errorf("invalid vector dimension: %d", cfg.dimension)
+	}
+	if cfg.prefix == "" {
+		cfg.prefix = cfg.index + ":"
+	}
+
+	store := &RedisStore{
+		client:   client,
+		cfg:      cfg,
+		tags:     make(map[string]bool),
+		numerics: make(map[string]bool),
+	}
+	for _, key := range cfg.tags {
+		if !identifier.MatchString(key) {
+			return nil, fmt.Errorf("invalid tag field: %q", key)
+		}
+		store.tags[key] = true
+	}
+	for _, key := range cfg.numerics {
+		if !identifier.MatchString(key) {
+			return nil, fmt.Errorf("invalid numeric field: %q", key)
+		}
+		if store.tags[key] {
+			return nil, fmt.Errorf("field %q cannot be both tag and numeric", key)
+		}
+		store.numerics[key] = true
+	}
+
+	if err := store.initIndex(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *RedisStore) initIndex(ctx context.Context) error {
+	info, err := s.client.FTInfo(ctx, s.cfg.index).Result()
+	if err != nil {
+		if !isUnknownIndex(err) {
+			return fmt.Errorf("failed to inspect index: %w", err)
+		}
+		return s.createIndex(ctx)
+	}
+
+	if s.matches(info) {
+		return nil
+	}
+
+	// Dropping the index keeps the document hashes, which are re-indexed by the new schema.
+	if err := s.client.FTDropIndex(ctx, s.cfg.index).Err(); err != nil {
+		return fmt.Errorf("failed to drop outdated index: %w", err)
+	}
+	return s.createIndex(ctx)
+}
+
+func (s *RedisStore) createIndex(ctx context.Context) error {
+	err := s.client.FTCreate(ctx, s.cfg.index, &redis.FTCreateOptions{
+		OnHash: true,
+		Prefix: []interface{}{s.cfg.prefix},
+	}, s.schema()...).Err()
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) schema() []*redis.FieldSchema {
+	vector := &redis.FTVectorArgs{}
+	if s.cfg.algorithm == AlgorithmFlat {
+		vector.FlatOptions = &redis.FTFlatOptions{
+			Type:           "FLOAT32",
+			Dim:            s.cfg.dimension,
+			DistanceMetric: string(s.cfg.distance),
+		}
+	} else {
+		vector.HNSWOptions = &redis.FTHNSWOptions{
+			Type:                   "FLOAT32",
+			Dim:                    s.cfg.dimension,
+			DistanceMetric:         string(s.cfg.distance),
+			MaxEdgesPerNode:        s.cfg.hnsw.M,
+			MaxAllowedEdgesPerNode: s.cfg.hnsw.EfConstruction,
+			EFRunTime:              s.cfg.hnsw.EfRuntime,
+		}
+	}
+
+	schema := []*redis.FieldSchema{
+		{FieldName: fieldID, FieldType: redis.SearchFieldTypeTag},
+		{FieldName: fieldEmbedding, FieldType: redis.SearchFieldTypeVector, VectorArgs: vector},
+	}
+	for _, key := range sortedKeys(s.tags) {
+		schema = append(schema, &redis.FieldSchema{FieldName: metadataPrefix + key, FieldType: redis.SearchFieldTypeTag})
+	}
+	for _, key := range sortedKeys(s.numerics) {
+		schema = append(schema, &redis.FieldSchema{FieldName: metadataPrefix + key, FieldType: redis.SearchFieldTypeNumeric})
+	}
+	return schema
+}
+
+// matches reports whether an existing index was created with the current options.
+func (s *RedisStore) matches(info redis.FTInfoResult) bool {
+	if len(info.IndexDefinition.Prefixes) != 1 || info.IndexDefinition.Prefixes[0] != s.cfg.prefix {
+		return false
+	}
+
+	expected := make(map[string]string)
+	for _, field := range s.schema() {
+		expected[field.FieldName] = field.FieldType.String()
+	}
+	if len(info.Attributes) != len(expected) {
+		return false
+	}
+
+	for _, attr := range info.Attributes {
+		fieldType, ok := expected[attr.Attribute]
+		if !ok || !strings.EqualFold(attr.Type, fieldType) {
+			return false
+		}
+		if attr.Attribute != fieldEmbedding {
+			continue
+		}
+		if attr.Dim != s.cfg.dimension ||
+			!strings.EqualFold(attr.DistanceMetric, string(s.cfg.distance)) ||
+			!strings.EqualFold(attr.Algorithm, string(s.cfg.algorithm)) {
+			return false
+		}
+		if s.cfg.algorithm == AlgorithmHNSW {
+			if s.cfg.hnsw.M > 0 && attr.M != s.cfg.hnsw.M {
+				return false
+			}
+			if s.cfg.hnsw.EfConstruction > 0 && attr.EFConstruction != s.cfg.hnsw.EfConstruction {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.cfg.prefix + id
+}
+
+func (s *RedisStore) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
+	if len(vectors) != len(documents) {
+		return fmt.Errorf("number of vectors and documents must match")
+	}
+
+	// MULTI keeps searches from seeing a document between the Del and the HSet.
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, doc := range documents {
+			if len(vectors[i]) != s.cfg.dimension {
+				return fmt.Errorf("vector for document %s has dimension %d, expected %d", doc.ID, len(vectors[i]), s.cfg.dimension)
+			}
+
+			fields, err := s.fields(doc, vectors[i])
+			if err != nil {
+				return err
+			}
+
+			// Replace the hash so metadata fields removed from the document disappear.
+			key := s.key(doc.ID)
+			pipe.Del(ctx, key)
+			pipe.HSet(ctx, key, fields)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert documents: %w", err)
+	}
+	return nil
+}
+
+// fields builds the hash for a document.
+func (s *RedisStore) fields(doc knowledge.Document, vector []float32) (map[string]interface{}, error) {
+	metadata := "{}"
+	if len(doc.Metadata) > 0 {
+		b, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata for document %s: %w", doc.ID, err)
+		}
+		metadata = string(b)
+	}
+
+	fields := map[string]interface{}{
+		fieldID:        doc.ID,
+		fieldContent:   doc.Content,
+		fieldMetadata:  metadata,
+		fieldEmbedding: encode(vector),
+	}
+
+	for key, value := range doc.Metadata {
+		switch {
+		case s.tags[key]:
+			tag, err := tagValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata %s for document %s: %w", key, doc.ID, err)
+			}
+			fields[metadataPrefix+key] = tag
+		case s.numerics[key]:
+			if n, ok := numericValue(value); ok {
+				fields[metadataPrefix+key] = n
+			}
+		}
+	}
+	return fields, nil
+}
+
+func (s *RedisStore) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter runs a KNN query restricted to documents whose metadata matches the filter.
+// Every filter key must be indexed with WithTagFields or WithNumericFields.
+func (s *RedisStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter knowledge.Filter) ([]knowledge.Document, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	prefilter, err := s.filterQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf("(%s)=>[KNN $k @%s $vec AS %s]", prefilter, fieldEmbedding, fieldDistance)
+	result, err := s.client.FTSearchWithArgs(ctx, s.cfg.index, q, &redis.FTSearchOptions{
+		Return: []redis.FTSearchReturn{
+			{FieldName: fieldID},
+			{FieldName: fieldContent},
+			{FieldName: fieldMetadata},
+			{FieldName: fieldDistance},
+		},
+		SortBy:         []redis.FTSearchSortBy{{FieldName: fieldDistance, Asc: true}},
+		Limit:          limit,
+		Params:         map[string]interface{}{"k": limit, "vec": encode(query)},
+		DialectVersion: 2,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	docs := make([]knowledge.Document, 0, len(result.Docs))
+	for _, hit := range result.Docs {
+		doc := knowledge.Document{
+			ID:      hit.Fields[fieldID],
+			Content: hit.Fields[fieldContent],
+		}
+		if doc.ID == "" {
+			doc.ID = strings.TrimPrefix(hit.ID, s.cfg.prefix)
+		}
+		if raw := hit.Fields[fieldMetadata]; raw != "" && raw != "{}" {
+			if err := json.Unmarshal([]byte(raw), &doc.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata for document %s: %w", doc.ID, err)
+			}
+		}
+		distance, err := strconv.ParseFloat(hit.Fields[fieldDistance], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid distance for document %s: %w", doc.ID, err)
+		}
+		doc.Score = s.cfg.distance.score(distance)
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// filterQuery translates a filter into a RediSearch query expression.
+func (s *RedisStore) filterQuery(filter knowledge.Filter) (string, error) {
+	if len(filter) == 0 {
+		return "*", nil
+	}
+
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var clauses []string
+	for _, key := range keys {
+		value := filter[key]
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
+		}
+
+		switch {
+		case s.tags[key]:
+			tags := make([]string, len(values))
+			for i, v := range values {
+				tag, err := tagValue(v)
+				if err != nil {
+					return "", fmt.Errorf("invalid filter value for tag field %s: %w", key, err)
+				}
+				tags[i] = escapeTag(tag)
+			}
+			clauses = append(clauses, fmt.Sprintf("@%s%s:{%s}", metadataPrefix, key, strings.Join(tags, " | ")))
+		case s.numerics[key]:
+			ranges := make([]string, len(values))
+			for i, v := range values {
+				n, ok := numericValue(v)
+				if !ok {
+					return "", fmt.Errorf("filter value for numeric field %s is not a number: %v", key, v)
+				}
+				ranges[i] = fmt.Sprintf("@%s%s:[%s %s]", metadataPrefix, key, n, n)
+			}
+			clauses = append(clauses, "("+strings.Join(ranges, " | ")+")")
+		default:
+			return "", fmt.Errorf("metadata field %q is not indexed", key)
+		}
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// Delete removes documents by ID. Unknown IDs are ignored.
+func (s *RedisStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	// Keys are deleted one by one so documents may live in different cluster slots.
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, id := range ids {
+			pipe.Del(ctx, s.key(id))
+		}
+		return nil
+	})
+	return err
+}
+
+func isUnknownIndex(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unknown index") || strings.Contains(msg, "no such index")
+}
+
+// tagSeparator splits a TAG field into tags.
+const tagSeparator = ","
+
+// tagValue renders a metadata value as a TAG field. Lists become comma-separated tags,
+// so values containing a comma are rejected.
+func tagValue(v interface{}) (string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = fmt.Sprint(item)
+		if strings.Contains(parts[i], tagSeparator) {
+			return "", fmt.Errorf("tag %q contains %q", parts[i], tagSeparator)
+		}
+	}
+	return strings.Join(parts, tagSeparator), nil
+}
+
+// numericValue renders a metadata value as a NUMERIC field. Booleans are stored as 1 and 0.
+func numericValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	case int:
+		return strconv.Itoa(v), true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// escapeTag escapes the characters RediSearch treats as syntax inside tag queries.
+func escapeTag(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if !(r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// encode serializes a vector as little-endian float32 values.
+func encode(vector []float32) []byte {
+	b := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
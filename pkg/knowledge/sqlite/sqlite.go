@@ -0,0 +1,339 @@
+package sqlite
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SQLiteStore implements knowledge.VectorStore using SQLite.
+// Vectors are stored as little-endian float32 blobs and scored by brute force,
+// or by the sqlite-vec extension when it is configured.
+type SQLiteStore struct {
+	db    *gorm.DB
+	table string
+	vec   bool
+}
+
+// DocumentModel represents the database schema for a document.
+type DocumentModel struct {
+	ID        string `gorm:"primaryKey"`
+	Content   string
+	Metadata  string // JSON object
+	Embedding []byte
+}
+
+// TableName overrides the table name.
+func (DocumentModel) TableName() string {
+	return "documents"
+}
+
+type config struct {
+	table        string
+	vecExtension string
+}
+
+// Option configures a SQLiteStore.
+type Option func(*config)
+
+// WithTableName sets the table that holds the documents. Use one table per knowledge base.
+func WithTableName(name string) Option {
+	return func(c *config) {
+		c.table = name
+	}
+}
+
+// WithVecExtension loads the sqlite-vec extension from path and uses it to score vectors in SQL.
+func WithVecExtension(path string) Option {
+	return func(c *config) {
+		c.vecExtension = path
+	}
+}
+
+// identifier matches table names that are safe to interpolate into SQL.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// drivers tracks the sql drivers registered for extension paths.
+var drivers sync.Map
+
+// New creates a new SQLiteStore.
+func New(dsn string, opts ...Option) (*SQLiteStore, error) {
+	cfg := config{table: "documents"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !identifier.MatchString(cfg.table) {
+		return nil, fmt.Errorf("invalid table name: %q", cfg.table)
+	}
+
+	dialector := sqlite.Dialector{DSN: dsn}
+	if cfg.vecExtension != "" {
+		dialector.DriverName = registerDriver(cfg.vecExtension)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	if err := db.Table(cfg.table).AutoMigrate(&DocumentModel{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return &SQLiteStore{
+		db:    db,
+		table: cfg.table,
+		vec:   cfg.vecExtension != "",
+	}, nil
+}
+
+// registerDriver registers a sqlite3 driver that loads the extension and returns its name.
+func registerDriver(extension string) string {
+	sum := sha256.Sum256([]byte(extension))
+	name := "sqlite3_talos_" + hex.EncodeToString(sum[:8])
+	if _, loaded := drivers.LoadOrStore(name, true); !loaded {
+		sql.Register(name, &sqlite3.SQLiteDriver{Extensions: []string{extension}})
+	}
+	return name
+}
+
+func (s *SQLiteStore) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
+	if len(vectors) != len(documents) {
+		return fmt.Errorf("number of vectors and documents must match")
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, doc := range documents {
+			metadata := "{}"
+			if len(doc.Metadata) > 0 {
+				b, err := json.Marshal(doc.Metadata)
+				if err != nil {
+					return fmt.Errorf("failed to marshal metadata for document %s: %w", doc.ID, err)
+				}
+				metadata = string(b)
+			}
+
+			model := DocumentModel{
+				ID:        doc.ID,
+				Content:   doc.Content,
+				Metadata:  metadata,
+				Embedding: encode(vectors[i]),
+			}
+
+			if err := tx.Table(s.table).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"content", "metadata", "embedding"}),
+			}).Create(&model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLiteStore) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
+	return s.SearchWithFilter(ctx, query, limit, nil)
+}
+
+// SearchWithFilter searches for similar documents whose metadata matches the filter.
+// Filter values must be scalars or lists of scalars.
+func (s *SQLiteStore) SearchWithFilter(ctx context.Context, query []float32, limit int, filter knowledge.Filter) ([]knowledge.Document, error) {
+	db := s.db.WithContext(ctx).Table(s.table)
+	for key, value := range filter {
+		cond, err := metadataCondition(key, value)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(cond)
+	}
+
+	if s.vec {
+		return s.searchVec(db, query, limit)
+	}
+	return s.searchBruteForce(db, query, limit)
+}
+
+// searchVec scores vectors in SQL with sqlite-vec.
+func (s *SQLiteStore) searchVec(db *gorm.DB, query []float32, limit int) ([]knowledge.Document, error) {
+	var results []struct {
+		DocumentModel
+		Distance float64
+	}
+	err := db.
+		Select("id, content, metadata, vec_distance_cosine(embedding, ?) AS distance", encode(query)).
+		Order("distance").
+		Limit(limit).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]knowledge.Document, len(results))
+	for i, r := range results {
+		doc, err := toDocument(r.DocumentModel)
+		if err != nil {
+			return nil, err
+		}
+		doc.Score = float32(1 - r.Distance)
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// searchBruteForce streams every matching row and keeps the top results.
+func (s *SQLiteStore) searchBruteForce(db *gorm.DB, query []float32, limit int) ([]knowledge.Document, error) {
+	rows, err := db.Select("id, content, metadata, embedding").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	best := &topK{}
+	for rows.Next() {
+		var model DocumentModel
+		if err := db.ScanRows(rows, &model); err != nil {
+			return nil, err
+		}
+
+		vector, err := decode(model.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embedding for document %s: %w", model.ID, err)
+		}
+		score := knowledge.CosineSimilarity(query, vector)
+		if best.Len() < limit {
+			heap.Push(best, scored{model: model, score: score})
+		} else if limit > 0 && score > (*best)[0].score {
+			(*best)[0] = scored{model: model, score: score}
+			heap.Fix(best, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	docs := make([]knowledge.Document, best.Len())
+	for i := len(docs) - 1; i >= 0; i-- {
+		item := heap.Pop(best).(scored)
+		doc, err := toDocument(item.model)
+		if err != nil {
+			return nil, err
+		}
+		doc.Score = item.score
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// Delete removes documents by ID. Unknown IDs are ignored.
+func (s *SQLiteStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Table(s.table).Where("id IN ?", ids).Delete(&DocumentModel{}).Error
+}
+
+// metadataCondition builds a json_extract condition for a single filter key.
+func metadataCondition(key string, value interface{}) (clause.Expression, error) {
+	if strings.ContainsAny(key, `"\`) {
+		return nil, fmt.Errorf("unsupported filter key: %q", key)
+	}
+	path := `$."` + key + `"`
+
+	if values, ok := value.([]interface{}); ok {
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			arg, err := sqlValue(key, v)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = arg
+		}
+		return clause.Expr{SQL: "json_extract(metadata, ?) IN ?", Vars: []interface{}{path, args}}, nil
+	}
+
+	arg, err := sqlValue(key, value)
+	if err != nil {
+		return nil, err
+	}
+	return clause.Expr{SQL: "json_extract(metadata, ?) = ?", Vars: []interface{}{path, arg}}, nil
+}
+
+// sqlValue converts a filter value to what json_extract returns for it.
+func sqlValue(key string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter value for key %s: %T", key, v)
+	}
+}
+
+func toDocument(m DocumentModel) (knowledge.Document, error) {
+	doc := knowledge.Document{ID: m.ID, Content: m.Content}
+	if m.Metadata != "" && m.Metadata != "{}" {
+		if err := json.Unmarshal([]byte(m.Metadata), &doc.Metadata); err != nil {
+			return doc, fmt.Errorf("failed to unmarshal metadata for document %s: %w", m.ID, err)
+		}
+	}
+	return doc, nil
+}
+
+// encode serializes a vector as little-endian float32 values, the format sqlite-vec expects.
+func encode(vector []float32) []byte {
+	b := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func decode(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector encoding: %d bytes", len(b))
+	}
+	vector := make([]float32, len(b)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return vector, nil
+}
+
+type scored struct {
+	model DocumentModel
+	score float32
+}
+
+// topK is a min-heap on score holding the best results seen so far.
+type topK []scored
+
+func (h topK) Len() int            { return len(h) }
+func (h topK) Less(i, j int) bool  { return h[i].score < h[j].score }
+func (h topK) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *topK) Push(x interface{}) { *h = append(*h, x.(scored)) }
+func (h *topK) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
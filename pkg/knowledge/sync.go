@@ -0,0 +1,388 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Deleter is implemented by vector stores that can remove documents.
+type Deleter interface {
+	// Delete removes documents by ID. Unknown IDs are ignored.
+	Delete(ctx context.Context, ids []string) error
+}
+
+// SourceFile is a single item of a corpus.
+type SourceFile struct {
+	// Path identifies the file within the source and must be stable between syncs.
+	Path     string
+	Content  string
+	Metadata map[string]interface{}
+}
+
+// Source enumerates the current contents of a corpus.
+type Source interface {
+	// Walk calls fn for every file in the source. Returning an error from fn stops the walk.
+	Walk(ctx context.Context, fn func(SourceFile) error) error
+}
+
+// DirSource is a Source reading files from a directory tree.
+type DirSource struct {
+	Root string
+	// Extensions restricts the files read, e.g. []string{".md", ".txt"}. Empty reads every file.
+	Extensions []string
+}
+
+// Walk implements Source. Paths are relative to Root and use forward slashes.
+func (d DirSource) Walk(ctx context.Context, fn func(SourceFile) error) error {
+	return filepath.WalkDir(d.Root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || !d.matches(path) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(d.Root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		return fn(SourceFile{
+			Path:     rel,
+			Content:  string(content),
+			Metadata: map[string]interface{}{MetadataSource: rel},
+		})
+	})
+}
+
+func (d DirSource) matches(path string) bool {
+	if len(d.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range d.Extensions {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Splitter splits file content into chunks. Each chunk reports its offsets in the content.
+type Splitter func(content string) []Chunk
+
+// Chunk is a piece of a file with its byte offsets.
+type Chunk struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// WholeFile is a Splitter that keeps each file as a single chunk.
+func WholeFile(content string) []Chunk {
+	return []Chunk{{Text: content, Start: 0, End: len(content)}}
+}
+
+// SplitBySize returns a Splitter producing chunks of at most size bytes, overlapping by overlap bytes.
+// Chunks end at whitespace where possible and never split a rune, so size is raised to
+// utf8.UTFMax if smaller. A negative overlap is treated as zero.
+func SplitBySize(size, overlap int) Splitter {
+	if size > 0 && size < utf8.UTFMax {
+		size = utf8.UTFMax
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+	return func(content string) []Chunk {
+		if size <= 0 || len(content) <= size {
+			return WholeFile(content)
+		}
+
+		var chunks []Chunk
+		for start := 0; start < len(content); {
+			end := start + size
+			if end >= len(content) {
+				end = len(content)
+			} else if cut := strings.LastIndexAny(content[start:end], " \n\t"); cut > size/2 {
+				end = start + cut
+			} else {
+				end = runeStart(content, end)
+			}
+			if end <= start {
+				// Always advance by at least one whole rune.
+				_, width := utf8.DecodeRuneInString(content[start:])
+				end = start + width
+			}
+			if strings.TrimSpace(content[start:end]) != "" {
+				chunks = append(chunks, Chunk{Text: content[start:end], Start: start, End: end})
+			}
+
+			if end == len(content) {
+				break
+			}
+			next := runeStart(content, end-overlap)
+			if next <= start {
+				next = end
+			}
+			start = next
+		}
+		return chunks
+	}
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence it points into.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// ManifestEntry records what was ingested for a file.
+type ManifestEntry struct {
+	Hash     string   `json:"hash"`
+	ChunkIDs []string `json:"chunk_ids"`
+}
+
+// Manifest maps source paths to their ingested state.
+type Manifest map[string]ManifestEntry
+
+// ManifestStore persists a Manifest between syncs.
+type ManifestStore interface {
+	Load(ctx context.Context) (Manifest, error)
+	Save(ctx context.Context, manifest Manifest) error
+}
+
+// FileManifest stores the manifest as a JSON file.
+type FileManifest struct {
+	Path string
+}
+
+// Load implements ManifestStore. A missing file yields an empty manifest.
+func (f FileManifest) Load(ctx context.Context) (Manifest, error) {
+	b, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := Manifest{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Save implements ManifestStore. The file is replaced atomically.
+func (f FileManifest) Save(ctx context.Context, manifest Manifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+// SyncOptions configures KnowledgeBase.Sync.
+type SyncOptions struct {
+	Manifest ManifestStore
+	// Splitter chunks each file. Defaults to WholeFile.
+	Splitter Splitter
+}
+
+// SyncResult summarizes a sync run.
+type SyncResult struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Deleted   int
+	// Chunks is the number of chunks embedded.
+	Chunks int
+}
+
+// Sync brings the knowledge base in line with the source. Only new or changed files are
+// embedded, and chunks of files that disappeared from the source are deleted.
+// Chunk IDs are "{path}#{index}" and carry source, start and end metadata.
+// The manifest is saved even if the sync fails partway, so finished files are not redone.
+func (kb *KnowledgeBase) Sync(ctx context.Context, src Source, opts SyncOptions) (result *SyncResult, err error) {
+	if opts.Manifest == nil {
+		return nil, fmt.Errorf("sync requires a manifest store")
+	}
+	splitter := opts.Splitter
+	if splitter == nil {
+		splitter = WholeFile
+	}
+
+	manifest, err := opts.Manifest.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	result = &SyncResult{}
+	defer func() {
+		if saveErr := opts.Manifest.Save(ctx, manifest); saveErr != nil && err == nil {
+			err = fmt.Errorf("failed to save manifest: %w", saveErr)
+		}
+	}()
+
+	seen := make(map[string]bool)
+	err = src.Walk(ctx, func(file SourceFile) error {
+		seen[file.Path] = true
+
+		sum := sha256.Sum256([]byte(file.Content))
+		hash := hex.EncodeToString(sum[:])
+
+		previous, existed := manifest[file.Path]
+		if existed && previous.Hash == hash {
+			result.Unchanged++
+			return nil
+		}
+
+		docs := chunkFile(file, splitter)
+		if len(docs) > 0 {
+			if err := kb.Ingest(ctx, docs); err != nil {
+				return fmt.Errorf("failed to ingest %s: %w", file.Path, err)
+			}
+		}
+
+		ids := make([]string, len(docs))
+		for i, doc := range docs {
+			ids[i] = doc.ID
+		}
+
+		if stale := difference(previous.ChunkIDs, ids); len(stale) > 0 {
+			if err := kb.delete(ctx, stale); err != nil {
+				return fmt.Errorf("failed to delete stale chunks of %s: %w", file.Path, err)
+			}
+		}
+
+		manifest[file.Path] = ManifestEntry{Hash: hash, ChunkIDs: ids}
+		result.Chunks += len(docs)
+		if existed {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var removed []string
+	for path := range manifest {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, path := range removed {
+		if err := kb.delete(ctx, manifest[path].ChunkIDs); err != nil {
+			return result, fmt.Errorf("failed to delete chunks of %s: %w", path, err)
+		}
+		delete(manifest, path)
+		result.Deleted++
+	}
+
+	return result, nil
+}
+
+// Watch runs Sync every interval until ctx is cancelled, reporting each run to onSync.
+func (kb *KnowledgeBase) Watch(ctx context.Context, src Source, opts SyncOptions, interval time.Duration, onSync func(*SyncResult, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := kb.Sync(ctx, src, opts)
+		if onSync != nil {
+			onSync(result, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (kb *KnowledgeBase) delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	deleter, ok := kb.VectorStore.(Deleter)
+	if !ok {
+		return fmt.Errorf("vector store %T does not support deletion", kb.VectorStore)
+	}
+	return deleter.Delete(ctx, ids)
+}
+
+// chunkFile splits a file into documents carrying the file metadata and chunk offsets.
+func chunkFile(file SourceFile, splitter Splitter) []Document {
+	chunks := splitter(file.Content)
+	docs := make([]Document, 0, len(chunks))
+	for i, chunk := range chunks {
+		if strings.TrimSpace(chunk.Text) == "" {
+			continue
+		}
+
+		metadata := make(map[string]interface{}, len(file.Metadata)+3)
+		for k, v := range file.Metadata {
+			metadata[k] = v
+		}
+		if _, ok := metadata[MetadataSource]; !ok {
+			metadata[MetadataSource] = file.Path
+		}
+		metadata[MetadataStart] = chunk.Start
+		metadata[MetadataEnd] = chunk.End
+
+		docs = append(docs, Document{
+			ID:       fmt.Sprintf("%s#%d", file.Path, i),
+			Content:  chunk.Text,
+			Metadata: metadata,
+		})
+	}
+	return docs
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	keep := make(map[string]bool, len(b))
+	for _, id := range b {
+		keep[id] = true
+	}
+
+	var result []string
+	for _, id := range a {
+		if !keep[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
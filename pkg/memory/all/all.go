@@ -0,0 +1,15 @@
+// Package all registers every built-in memory driver with memory.NewFactory.
+//
+//	import _ "github.com/barekit/talos/pkg/memory/all"
+package all
+
+import (
+	_ "github.com/barekit/talos/pkg/memory/inmemory"
+	_ "github.com/barekit/talos/pkg/memory/mongo"
+	_ "github.com/barekit/talos/pkg/memory/mssql"
+	_ "github.com/barekit/talos/pkg/memory/mysql"
+	_ "github.com/barekit/talos/pkg/memory/neo4j"
+	_ "github.com/barekit/talos/pkg/memory/postgres"
+	_ "github.com/barekit/talos/pkg/memory/redis"
+	_ "github.com/barekit/talos/pkg/memory/sqlite"
+)
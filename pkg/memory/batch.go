@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/barekit/talos/pkg/llm"
+)
+
+// BatchSaver is implemented by adapters that can append several messages in one
+// round-trip or transaction. Either all messages become visible to Load or none do.
+type BatchSaver interface {
+	SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error
+}
+
+// SaveBatch saves msgs with m's SaveBatch when it implements BatchSaver.
+// Otherwise the messages are saved one by one, which is not atomic.
+func SaveBatch(ctx context.Context, m Memory, sessionID string, msgs []llm.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	if b, ok := m.(BatchSaver); ok {
+		return b.SaveBatch(ctx, sessionID, msgs)
+	}
+	for _, msg := range msgs {
+		if err := m.Save(ctx, sessionID, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/llm"
+)
+
+// ErrMessageNotFound is returned when a message ID does not belong to the session.
+var ErrMessageNotFound = errors.New("message not found")
+
+// Node is a stored message with its place in the conversation tree.
+type Node struct {
+	ID string
+	// ParentID is empty for the first message of the session.
+	ParentID string
+	// Seq is the position of the message in the session's write order, starting at 1.
+	// It is 0 for messages stored before sequence numbers were introduced.
+	Seq     int64
+	Message llm.Message
+}
+
+// Brancher is implemented by adapters that keep a session's messages as a tree.
+// Save appends to the active branch, whose last message is the session head,
+// and Load returns the active branch from its first message to the head.
+type Brancher interface {
+	// History returns the active branch with message IDs.
+	History(ctx context.Context, sessionID string) ([]Node, error)
+	// Fork moves the head to the parent of messageID, so the next Save starts a sibling of it.
+	// This is how an earlier prompt is edited: the original branch stays available via Branches.
+	Fork(ctx context.Context, sessionID, messageID string) error
+	// Checkout makes messageID the head. An empty ID moves the head before the first message.
+	Checkout(ctx context.Context, sessionID, messageID string) error
+	// Branches returns the last message of every branch, in creation order.
+	Branches(ctx context.Context, sessionID string) ([]Node, error)
+}
+
+// BranchPath returns the path from the root to headID through the nodes' parent links.
+func BranchPath(nodes []Node, headID string) ([]Node, error) {
+	byID := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	var path []Node
+	for id := headID; id != ""; {
+		n, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, id)
+		}
+		if len(path) > len(nodes) {
+			return nil, fmt.Errorf("cycle in message tree at %s", id)
+		}
+		path = append(path, n)
+		id = n.ParentID
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// Leaves returns the nodes without children, keeping their order.
+func Leaves(nodes []Node) []Node {
+	hasChildren := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if n.ParentID != "" {
+			hasChildren[n.ParentID] = true
+		}
+	}
+
+	var leaves []Node
+	for _, n := range nodes {
+		if !hasChildren[n.ID] {
+			leaves = append(leaves, n)
+		}
+	}
+	return leaves
+}
+
+// ParentOf returns the parent ID of messageID among nodes.
+func ParentOf(nodes []Node, messageID string) (string, error) {
+	for _, n := range nodes {
+		if n.ID == messageID {
+			return n.ParentID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+}
+
+// Messages returns the messages of a path of nodes.
+func Messages(nodes []Node) []llm.Message {
+	messages := make([]llm.Message, len(nodes))
+	for i, n := range nodes {
+		messages[i] = n.Message
+	}
+	return messages
+}
@@ -7,16 +7,36 @@ const (
 	// TableNameMessages is the default table/collection name for messages.
 	TableNameMessages = "messages"
 
+	// TableNameSessions is the default table/collection name for session records.
+	TableNameSessions = "sessions"
+
 	// Column names
 	ColSessionID  = "session_id"
+	ColMessageID  = "message_id"
+	ColParentID   = "parent_id"
+	ColHeadID     = "head_id"
+	ColSeq        = "seq"
+	ColLastSeq    = "last_seq"
+	ColTenantID   = "tenant_id"
+	ColUserID     = "user_id"
+	ColTitle      = "title"
+	ColTags       = "tags"
+	ColAttributes = "attributes"
 	ColRole       = "role"
 	ColContent    = "content"
 	ColToolCalls  = "tool_calls"
 	ColToolCallID = "tool_call_id"
 	ColCreatedAt  = "created_at"
+	ColUpdatedAt  = "updated_at"
+	ColExpiresAt  = "expires_at"
 
 	// Neo4j specific
 	LabelSession  = "Session"
 	LabelMessage  = "Message"
 	RelHasMessage = "HAS_MESSAGE"
+	RelFollows    = "FOLLOWS"
+	LabelEntity   = "Entity"
+	RelRelatesTo  = "RELATES_TO"
+	RelMentions   = "MENTIONS"
+	LabelSchema   = "TalosSchema"
 )
@@ -0,0 +1,36 @@
+package encrypt
+
+import (
+	"context"
+
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// History implements memory.Brancher.
+func (m *Memory) History(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	nodes, err := m.Wrapper.History(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.decryptNodes(ctx, sessionID, nodes)
+}
+
+// Branches implements memory.Brancher.
+func (m *Memory) Branches(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	nodes, err := m.Wrapper.Branches(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return m.decryptNodes(ctx, sessionID, nodes)
+}
+
+func (m *Memory) decryptNodes(ctx context.Context, sessionID string, nodes []memory.Node) ([]memory.Node, error) {
+	for i := range nodes {
+		msg, err := m.decryptMessage(ctx, sessionID, nodes[i].Message)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i].Message = msg
+	}
+	return nodes, nil
+}
@@ -0,0 +1,181 @@
+// Package encrypt provides a memory.Memory decorator that encrypts message content at rest.
+package encrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// prefix marks encrypted values: "enc:v1:{keyID}:{base64(nonce|ciphertext)}".
+const prefix = "enc:v1:"
+
+// Memory encrypts message content, tool call arguments and attachments with AES-GCM
+// before saving them to the wrapped memory, and decrypts them on load.
+// Roles, tool names and IDs stay in plaintext so adapters can still index them.
+//
+// Ciphertexts are bound to their tenant and session, so they cannot be copied into
+// another session. Values without the encryption prefix, written before encryption
+// was enabled, are returned as they are. Session metadata is not encrypted.
+type Memory struct {
+	memory.Wrapper
+	keys KeyProvider
+}
+
+// New wraps a Memory.
+func New(inner memory.Memory, keys KeyProvider) *Memory {
+	return &Memory{Wrapper: memory.Wrapper{Inner: inner}, keys: keys}
+}
+
+// Save encrypts and saves a message.
+func (m *Memory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+	encrypted, err := m.encryptMessage(ctx, sessionID, msg)
+	if err != nil {
+		return err
+	}
+	return m.Inner.Save(ctx, sessionID, encrypted)
+}
+
+// SaveBatch encrypts and saves messages with the wrapped memory's batch save.
+func (m *Memory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	encrypted := make([]llm.Message, len(msgs))
+	for i, msg := range msgs {
+		var err error
+		if encrypted[i], err = m.encryptMessage(ctx, sessionID, msg); err != nil {
+			return err
+		}
+	}
+	return memory.SaveBatch(ctx, m.Inner, sessionID, encrypted)
+}
+
+// Load loads and decrypts the messages of a session.
+func (m *Memory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
+	msgs, err := m.Inner.Load(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range msgs {
+		if msgs[i], err = m.decryptMessage(ctx, sessionID, msgs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return msgs, nil
+}
+
+func (m *Memory) encryptMessage(ctx context.Context, sessionID string, msg llm.Message) (llm.Message, error) {
+	tenantID := memory.TenantFromContext(ctx)
+	key, err := m.keys.CurrentKey(ctx, tenantID)
+	if err != nil {
+		return msg, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return msg, err
+	}
+
+	seal := func(s string) (string, error) {
+		if s == "" {
+			return s, nil
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		sealed := aead.Seal(nonce, nonce, []byte(s), additionalData(tenantID, sessionID))
+		return prefix + key.ID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+	}
+	return transform(msg, seal)
+}
+
+func (m *Memory) decryptMessage(ctx context.Context, sessionID string, msg llm.Message) (llm.Message, error) {
+	tenantID := memory.TenantFromContext(ctx)
+	aeads := make(map[string]cipher.AEAD)
+
+	open := func(s string) (string, error) {
+		if !strings.HasPrefix(s, prefix) {
+			return s, nil
+		}
+		keyID, payload, ok := strings.Cut(s[len(prefix):], ":")
+		if !ok {
+			return "", errors.New("malformed encrypted value")
+		}
+
+		aead, ok := aeads[keyID]
+		if !ok {
+			key, err := m.keys.Key(ctx, tenantID, keyID)
+			if err != nil {
+				return "", fmt.Errorf("failed to get decryption key: %w", err)
+			}
+			if aead, err = newAEAD(key); err != nil {
+				return "", err
+			}
+			aeads[keyID] = aead
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", errors.New("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(tenantID, sessionID))
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt message with key %s: %w", keyID, err)
+		}
+		return string(plaintext), nil
+	}
+	return transform(msg, open)
+}
+
+// transform applies fn to every encrypted field, copying slices so the caller's message is untouched.
+func transform(msg llm.Message, fn func(string) (string, error)) (llm.Message, error) {
+	var err error
+	if msg.Content, err = fn(msg.Content); err != nil {
+		return msg, err
+	}
+
+	if len(msg.ToolCalls) > 0 {
+		toolCalls := make([]llm.ToolCall, len(msg.ToolCalls))
+		copy(toolCalls, msg.ToolCalls)
+		for i := range toolCalls {
+			if toolCalls[i].Function.Arguments, err = fn(toolCalls[i].Function.Arguments); err != nil {
+				return msg, err
+			}
+		}
+		msg.ToolCalls = toolCalls
+	}
+
+	if len(msg.Attachments) > 0 {
+		attachments := make([]llm.Attachment, len(msg.Attachments))
+		copy(attachments, msg.Attachments)
+		for i := range attachments {
+			if attachments[i].URL, err = fn(attachments[i].URL); err != nil {
+				return msg, err
+			}
+			if attachments[i].Data, err = fn(attachments[i].Data); err != nil {
+				return msg, err
+			}
+		}
+		msg.Attachments = attachments
+	}
+	return msg, nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Material)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %s: %w", key.ID, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func additionalData(tenantID, sessionID string) []byte {
+	return []byte(tenantID + "\x00" + sessionID)
+}
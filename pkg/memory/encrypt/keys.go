@@ -0,0 +1,109 @@
+package encrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned by a KeyProvider that has no key for a tenant or key ID.
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// Key is an AES key with the ID stored next to every value it encrypts.
+type Key struct {
+	ID string
+	// Material is 16, 24 or 32 bytes for AES-128, AES-192 or AES-256.
+	Material []byte
+}
+
+// KeyProvider supplies per-tenant keys. Implementations typically wrap a KMS or secret manager.
+type KeyProvider interface {
+	// CurrentKey returns the key used to encrypt new messages of the tenant.
+	CurrentKey(ctx context.Context, tenantID string) (Key, error)
+	// Key returns a key by ID, so messages written before a rotation stay readable.
+	Key(ctx context.Context, tenantID, keyID string) (Key, error)
+}
+
+// KeyRing is an in-process KeyProvider. Keys added for the empty tenant are used
+// by tenants that have no keys of their own.
+type KeyRing struct {
+	mu      sync.RWMutex
+	keys    map[string]map[string]Key
+	current map[string]string
+}
+
+// NewKeyRing creates an empty KeyRing.
+func NewKeyRing() *KeyRing {
+	return &KeyRing{
+		keys:    make(map[string]map[string]Key),
+		current: make(map[string]string),
+	}
+}
+
+// Add registers a key for a tenant. The first key of a tenant becomes its current key.
+func (r *KeyRing) Add(tenantID string, key Key) error {
+	if key.ID == "" || strings.Contains(key.ID, ":") {
+		return fmt.Errorf("invalid key ID %q", key.ID)
+	}
+	switch len(key.Material) {
+	case 16, 24, 32:
+	default:
+		return fmt.Errorf("invalid key size %d for key %s", len(key.Material), key.ID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keys[tenantID] == nil {
+		r.keys[tenantID] = make(map[string]Key)
+	}
+	r.keys[tenantID][key.ID] = Key{ID: key.ID, Material: append([]byte(nil), key.Material...)}
+	if r.current[tenantID] == "" {
+		r.current[tenantID] = key.ID
+	}
+	return nil
+}
+
+// Rotate adds a key and makes it the tenant's current key. Older keys are kept for decryption.
+func (r *KeyRing) Rotate(tenantID string, key Key) error {
+	if err := r.Add(tenantID, key); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current[tenantID] = key.ID
+	return nil
+}
+
+// CurrentKey implements KeyProvider.
+func (r *KeyRing) CurrentKey(ctx context.Context, tenantID string) (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.keys[tenantID]; !ok {
+		tenantID = ""
+	}
+	id, ok := r.current[tenantID]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return r.keys[tenantID][id], nil
+}
+
+// Key implements KeyProvider. Keys of the empty tenant are found as well, since the
+// tenant may have used them before it was given keys of its own.
+func (r *KeyRing) Key(ctx context.Context, tenantID, keyID string) (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if key, ok := r.keys[tenantID][keyID]; ok {
+		return key, nil
+	}
+	if key, ok := r.keys[""][keyID]; ok {
+		return key, nil
+	}
+	return Key{}, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+}
@@ -0,0 +1,195 @@
+// Package entity provides entity memory: facts about people, organizations and other
+// things mentioned in conversations, kept as a graph and recalled on later turns.
+package entity
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entity is a node of the graph. Entities are identified by name, case-insensitively.
+type Entity struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// Relation is a directed edge between two entities, e.g. "Alice" WORKS_AT "Acme".
+type Relation struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// Graph is a set of entities and relations.
+type Graph struct {
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+}
+
+// Empty reports whether the graph has no entities and no relations.
+func (g Graph) Empty() bool {
+	return len(g.Entities) == 0 && len(g.Relations) == 0
+}
+
+// Store persists the entity graph. Implementations scope it to the tenant of the context.
+type Store interface {
+	// UpsertGraph merges entities and relations into the graph. Properties of existing entities
+	// are updated; relation endpoints missing from the graph are created. sessionID records
+	// where the entities were mentioned and may be empty.
+	UpsertGraph(ctx context.Context, sessionID string, graph Graph) error
+	// RecallGraph returns the entities whose names appear in text, their direct neighbours
+	// and the relations between them. At most limit entities are matched in text.
+	RecallGraph(ctx context.Context, text string, limit int) (Graph, error)
+}
+
+var nonWord = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// Key normalizes an entity name for matching.
+func Key(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// RelationType normalizes a relation type to upper snake case, e.g. "works at" to "WORKS_AT".
+func RelationType(s string) string {
+	return strings.Trim(nonWord.ReplaceAllString(strings.ToUpper(s), "_"), "_")
+}
+
+// Mentions reports whether text mentions name as a whole word, case-insensitively.
+// text must already be normalized with Key.
+func Mentions(text, name string) bool {
+	count, _ := mentions(text, Key(name))
+	return count > 0
+}
+
+// mentions counts the whole-word occurrences of key in text and returns the first one's offset.
+func mentions(text, key string) (count, first int) {
+	first = -1
+	if len(key) < 2 {
+		return 0, first
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], key)
+		if i < 0 {
+			return count, first
+		}
+		i += start
+		end := i + len(key)
+		if (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end])) {
+			if count == 0 {
+				first = i
+			}
+			count++
+		}
+		start = i + 1
+	}
+}
+
+// Rank orders entity keys mentioned in text by relevance: the most mentioned first, then
+// the earliest mentioned. text must already be normalized with Key.
+func Rank(text string, keys []string) []string {
+	type ranked struct {
+		key          string
+		count, first int
+	}
+	rs := make([]ranked, len(keys))
+	for i, key := range keys {
+		count, first := mentions(text, key)
+		rs[i] = ranked{key, count, first}
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		a, b := rs[i], rs[j]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if a.first != b.first {
+			return a.first < b.first
+		}
+		return a.key < b.key
+	})
+
+	result := make([]string, len(rs))
+	for i, r := range rs {
+		result[i] = r.key
+	}
+	return result
+}
+
+// MaxNameWords is the longest entity name, in words, that Candidates produces.
+const MaxNameWords = 6
+
+// Candidates returns every key an entity mentioned in text could have: the spans of text
+// that start and end at word boundaries and have at most MaxNameWords words. Stores that
+// cannot match names against text look entities up by these keys.
+func Candidates(text string) []string {
+	text = Key(text)
+
+	var starts, ends []int
+	for i := 0; i < len(text); i++ {
+		if text[i] == ' ' {
+			continue
+		}
+		if i == 0 || !isWordByte(text[i-1]) {
+			starts = append(starts, i)
+		}
+		if i+1 == len(text) || !isWordByte(text[i+1]) {
+			ends = append(ends, i+1)
+		}
+	}
+
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, start := range starts {
+		for _, end := range ends[sort.SearchInts(ends, start+1):] {
+			key := text[start:end]
+			if strings.Count(key, " ") >= MaxNameWords {
+				break
+			}
+			if len(key) >= 2 && !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, key)
+			}
+		}
+	}
+	return candidates
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 0x80
+}
+
+// Format renders a graph as a list of facts for the prompt.
+func Format(g Graph) string {
+	if g.Empty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Known facts from earlier conversations:\n")
+	for _, e := range g.Entities {
+		fmt.Fprintf(&b, "- %s", e.Name)
+		if e.Type != "" {
+			fmt.Fprintf(&b, " (%s)", e.Type)
+		}
+		if len(e.Properties) > 0 {
+			keys := make([]string, 0, len(e.Properties))
+			for k := range e.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			pairs := make([]string, len(keys))
+			for i, k := range keys {
+				pairs[i] = k + ": " + e.Properties[k]
+			}
+			fmt.Fprintf(&b, ": %s", strings.Join(pairs, ", "))
+		}
+		b.WriteString("\n")
+	}
+	for _, r := range g.Relations {
+		fmt.Fprintf(&b, "- %s %s %s\n", r.Source, strings.ToLower(strings.ReplaceAll(r.Type, "_", " ")), r.Target)
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/barekit/talos/pkg/llm"
+)
+
+const extractPrompt = `You extract a knowledge graph from a conversation.
+List the people, organizations, products, places and requests that are mentioned, and the relations between them.
+Only include facts stated in the conversation. Use short canonical names, e.g. "Alice Smith" rather than "she".
+Reply with JSON only, in this form:
+{"entities": [{"name": "Alice Smith", "type": "person", "properties": {"role": "CFO"}}],
+ "relations": [{"source": "Alice Smith", "target": "Acme", "type": "WORKS_AT"}]}`
+
+// Extractor extracts entities and relations from messages with an LLM.
+type Extractor struct {
+	provider llm.Provider
+}
+
+// NewExtractor creates an Extractor.
+func NewExtractor(provider llm.Provider) *Extractor {
+	return &Extractor{provider: provider}
+}
+
+// Extract returns the graph described by the user and assistant messages.
+func (e *Extractor) Extract(ctx context.Context, messages []llm.Message) (Graph, error) {
+	var transcript []string
+	for _, msg := range messages {
+		if (msg.Role == llm.RoleUser || msg.Role == llm.RoleAssistant) && msg.Content != "" {
+			transcript = append(transcript, fmt.Sprintf("%s: %s", msg.Role, msg.Content))
+		}
+	}
+	if len(transcript) == 0 {
+		return Graph{}, nil
+	}
+
+	resp, err := e.provider.Chat(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: extractPrompt},
+		{Role: llm.RoleUser, Content: strings.Join(transcript, "\n")},
+	}, nil)
+	if err != nil {
+		return Graph{}, fmt.Errorf("failed to extract entities: %w", err)
+	}
+	return Parse(resp.Content)
+}
+
+// Parse reads a graph from a model reply, tolerating code fences around the JSON.
+// Relations are normalized and those with a missing endpoint or type are dropped.
+func Parse(reply string) (Graph, error) {
+	reply = strings.TrimSpace(reply)
+	if start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); start >= 0 && end > start {
+		reply = reply[start : end+1]
+	}
+
+	var g Graph
+	if err := json.Unmarshal([]byte(reply), &g); err != nil {
+		return Graph{}, fmt.Errorf("failed to parse extracted entities: %w", err)
+	}
+
+	entities := g.Entities[:0]
+	for _, ent := range g.Entities {
+		if ent.Name = strings.TrimSpace(ent.Name); ent.Name != "" {
+			ent.Type = strings.ToLower(strings.TrimSpace(ent.Type))
+			entities = append(entities, ent)
+		}
+	}
+	relations := g.Relations[:0]
+	for _, r := range g.Relations {
+		r.Source, r.Target, r.Type = strings.TrimSpace(r.Source), strings.TrimSpace(r.Target), RelationType(r.Type)
+		if r.Source != "" && r.Target != "" && r.Type != "" {
+			relations = append(relations, r)
+		}
+	}
+	return Graph{Entities: entities, Relations: relations}, nil
+}
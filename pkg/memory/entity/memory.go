@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/llm"
+)
+
+// Memory combines an Extractor and a Store: it remembers the entities of each turn
+// and recalls the relevant part of the graph for the next one.
+type Memory struct {
+	Store     Store
+	Extractor *Extractor
+	// Limit caps the number of entities matched per recall. Defaults to 10.
+	Limit int
+}
+
+// New creates an entity Memory extracting with provider.
+func New(store Store, provider llm.Provider) *Memory {
+	return &Memory{Store: store, Extractor: NewExtractor(provider), Limit: 10}
+}
+
+// Remember extracts entities from messages and merges them into the store.
+func (m *Memory) Remember(ctx context.Context, sessionID string, messages []llm.Message) error {
+	graph, err := m.Extractor.Extract(ctx, messages)
+	if err != nil {
+		return err
+	}
+	if graph.Empty() {
+		return nil
+	}
+	if err := m.Store.UpsertGraph(ctx, sessionID, graph); err != nil {
+		return fmt.Errorf("failed to store entities: %w", err)
+	}
+	return nil
+}
+
+// Recall returns the facts known about the entities mentioned in text, formatted for the prompt.
+// It returns an empty string when nothing is known.
+func (m *Memory) Recall(ctx context.Context, text string) (string, error) {
+	limit := m.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	graph, err := m.Store.RecallGraph(ctx, text, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to recall entities: %w", err)
+	}
+	return Format(graph), nil
+}
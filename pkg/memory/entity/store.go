@@ -0,0 +1,141 @@
+package entity
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// InMemoryStore is an in-process Store for tests and single-process deployments.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	tenants map[string]*tenantGraph
+}
+
+type tenantGraph struct {
+	entities  map[string]*Entity
+	relations map[Relation]bool
+}
+
+// NewInMemoryStore creates an empty InMemoryStore.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{tenants: make(map[string]*tenantGraph)}
+}
+
+// UpsertGraph implements Store.
+func (s *InMemoryStore) UpsertGraph(ctx context.Context, sessionID string, graph Graph) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID := memory.TenantFromContext(ctx)
+	g, ok := s.tenants[tenantID]
+	if !ok {
+		g = &tenantGraph{entities: make(map[string]*Entity), relations: make(map[Relation]bool)}
+		s.tenants[tenantID] = g
+	}
+
+	upsert := func(e Entity) {
+		existing, ok := g.entities[Key(e.Name)]
+		if !ok {
+			existing = &Entity{Name: e.Name}
+			g.entities[Key(e.Name)] = existing
+		}
+		if e.Type != "" {
+			existing.Type = e.Type
+		}
+		for k, v := range e.Properties {
+			if existing.Properties == nil {
+				existing.Properties = make(map[string]string)
+			}
+			existing.Properties[k] = v
+		}
+	}
+
+	for _, e := range graph.Entities {
+		upsert(e)
+	}
+	for _, r := range graph.Relations {
+		upsert(Entity{Name: r.Source})
+		upsert(Entity{Name: r.Target})
+		g.relations[Relation{
+			Source: g.entities[Key(r.Source)].Name,
+			Target: g.entities[Key(r.Target)].Name,
+			Type:   RelationType(r.Type),
+		}] = true
+	}
+	return nil
+}
+
+// RecallGraph implements Store.
+func (s *InMemoryStore) RecallGraph(ctx context.Context, text string, limit int) (Graph, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g, ok := s.tenants[memory.TenantFromContext(ctx)]
+	if !ok {
+		return Graph{}, nil
+	}
+
+	text = Key(text)
+	var seeds []string
+	for key, e := range g.entities {
+		if Mentions(text, e.Name) {
+			seeds = append(seeds, key)
+		}
+	}
+	seeds = Rank(text, seeds)
+	if limit > 0 && len(seeds) > limit {
+		seeds = seeds[:limit]
+	}
+
+	included := make(map[string]bool)
+	for _, key := range seeds {
+		included[key] = true
+	}
+	var result Graph
+	for r := range g.relations {
+		if included[Key(r.Source)] || included[Key(r.Target)] {
+			result.Relations = append(result.Relations, r)
+		}
+	}
+	for _, r := range result.Relations {
+		included[Key(r.Source)] = true
+		included[Key(r.Target)] = true
+	}
+
+	for key := range included {
+		e := *g.entities[key]
+		e.Properties = copyProperties(e.Properties)
+		result.Entities = append(result.Entities, e)
+	}
+	result.Sort()
+	return result, nil
+}
+
+func copyProperties(props map[string]string) map[string]string {
+	if props == nil {
+		return nil
+	}
+	result := make(map[string]string, len(props))
+	for k, v := range props {
+		result[k] = v
+	}
+	return result
+}
+
+// Sort orders entities by name and relations by source, type and target.
+func (g *Graph) Sort() {
+	sort.Slice(g.Entities, func(i, j int) bool { return g.Entities[i].Name < g.Entities[j].Name })
+	sort.Slice(g.Relations, func(i, j int) bool {
+		a, b := g.Relations[i], g.Relations[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Target < b.Target
+	})
+}
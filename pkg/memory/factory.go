@@ -3,19 +3,10 @@ package memory
 import (
 	"context"
 	"fmt"
-
-	"github.com/barekit/talos/pkg/memory/consts"
-	"github.com/barekit/talos/pkg/memory/inmemory"
-	mongomem "github.com/barekit/talos/pkg/memory/mongo"
-	"github.com/barekit/talos/pkg/memory/mssql"
-	"github.com/barekit/talos/pkg/memory/mysql"
-	"github.com/barekit/talos/pkg/memory/neo4j"
-	"github.com/barekit/talos/pkg/memory/postgres"
-	"github.com/barekit/talos/pkg/memory/redis"
-	"github.com/barekit/talos/pkg/memory/sqlite"
-	goredis "github.com/redis/go-redis/v9"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Type string
@@ -33,66 +24,111 @@ const (
 
 // Config holds configuration for memory adapters.
 type Config struct {
+	// Type selects the driver. When empty, it is derived from the scheme of ConnectionString.
 	Type             Type
 	ConnectionString string
 	Username         string
 	Password         string
 	DBName           string
+	// Retention expires sessions that have not been written to for this long.
+	// Zero keeps history forever. The in-memory adapter ignores it.
+	Retention time.Duration
+	// SweepInterval is how often adapters without native expiry (SQL, Mongo, Neo4j) delete
+	// expired sessions. Zero uses Retention, capped at one hour.
+	SweepInterval time.Duration
 	// Additional options can be added here (e.g., Redis options, Neo4j auth)
 }
 
-// NewFactory creates a new memory adapter based on the configuration.
-func NewFactory(ctx context.Context, cfg Config) (Memory, error) {
-	switch cfg.Type {
-	case TypeSQLite:
-		return sqlite.New(cfg.ConnectionString)
+// Driver opens a Memory for a configuration.
+type Driver func(ctx context.Context, cfg Config) (Memory, error)
 
-	case TypePostgres:
-		return postgres.New(cfg.ConnectionString)
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[Type]Driver)
+	schemes   = make(map[string]Type)
+)
 
-	case TypeRedis:
-		opts, err := goredis.ParseURL(cfg.ConnectionString)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse redis url: %w", err)
-		}
-		client := goredis.NewClient(opts)
-		if err := client.Ping(ctx).Err(); err != nil {
-			return nil, fmt.Errorf("failed to ping redis: %w", err)
-		}
-		return redis.New(client), nil
+// Register makes a driver available to NewFactory under typ, and for connection strings
+// using any of the given URL schemes. Adapter packages call it from init, so a binary only
+// contains the backends it imports. Register panics if typ or a scheme is already registered.
+func Register(typ Type, driver Driver, urlSchemes ...string) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
 
-	case TypeNeo4j:
-		dbName := "neo4j" // Default Neo4j DB is typically "neo4j", not "talos"
-		if cfg.DBName != "" {
-			dbName = cfg.DBName
+	if driver == nil {
+		panic("memory: Register driver is nil")
+	}
+	if _, dup := drivers[typ]; dup {
+		panic(fmt.Sprintf("memory: Register called twice for type %s", typ))
+	}
+	for _, scheme := range urlSchemes {
+		scheme = strings.ToLower(scheme)
+		if other, dup := schemes[scheme]; dup {
+			panic(fmt.Sprintf("memory: scheme %s already registered by type %s", scheme, other))
 		}
-		return neo4j.New(cfg.ConnectionString, cfg.Username, cfg.Password, dbName)
+		schemes[scheme] = typ
+	}
+	drivers[typ] = driver
+}
 
-	case TypeMongo:
-		opts := options.Client().ApplyURI(cfg.ConnectionString)
-		client, err := mongo.Connect(ctx, opts)
-		if err != nil {
-			return nil, fmt.Errorf("failed to connect to mongo: %w", err)
-		}
-		if err := client.Ping(ctx, nil); err != nil {
-			return nil, fmt.Errorf("failed to ping mongo: %w", err)
-		}
-		dbName := consts.DefaultDBName
-		if cfg.DBName != "" {
-			dbName = cfg.DBName
+// Types returns the registered memory types in sorted order.
+func Types() []Type {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	types := make([]Type, 0, len(drivers))
+	for typ := range drivers {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// NewFactory creates a new memory adapter based on the configuration.
+// The adapter package must be imported for its driver to be registered, e.g.
+//
+//	import _ "github.com/barekit/talos/pkg/memory/sqlite"
+//
+// or pkg/memory/all for every built-in backend.
+func NewFactory(ctx context.Context, cfg Config) (Memory, error) {
+	typ := cfg.Type
+	if typ == "" {
+		scheme := Scheme(cfg.ConnectionString)
+		if scheme == "" {
+			return nil, fmt.Errorf("memory type not set and connection string has no scheme")
 		}
-		return mongomem.New(client, dbName, consts.TableNameMessages), nil
 
-	case TypeMySQL:
-		return mysql.New(cfg.ConnectionString)
+		driversMu.RLock()
+		t, ok := schemes[scheme]
+		driversMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("no memory driver registered for scheme %q", scheme)
+		}
+		typ = t
+	}
 
-	case TypeMSSQL:
-		return mssql.New(cfg.ConnectionString)
+	driversMu.RLock()
+	driver, ok := drivers[typ]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported memory type: %s (is its package imported?)", typ)
+	}
 
-	case TypeInMemory:
-		return inmemory.New(), nil
+	cfg.Type = typ
+	return driver(ctx, cfg)
+}
 
-	default:
-		return nil, fmt.Errorf("unsupported memory type: %s", cfg.Type)
+// Scheme returns the lower-cased URL scheme of a connection string, or "" if it has none.
+func Scheme(dsn string) string {
+	i := strings.Index(dsn, "://")
+	if i <= 0 {
+		return ""
+	}
+	scheme := dsn[:i]
+	for _, r := range scheme {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.') {
+			return ""
+		}
 	}
+	return strings.ToLower(scheme)
 }
@@ -0,0 +1,165 @@
+package gorm
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"gorm.io/gorm"
+)
+
+// History implements memory.Brancher.
+func (m *Memory) History(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	tenantID := memory.TenantFromContext(ctx)
+	db := m.db.WithContext(ctx)
+
+	nodes, err := loadNodes(db, tenantID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	headID, _, err := head(db, tenantID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.BranchPath(nodes, formatID(headID))
+}
+
+// Fork implements memory.Brancher.
+func (m *Memory) Fork(ctx context.Context, sessionID, messageID string) error {
+	tenantID := memory.TenantFromContext(ctx)
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		nodes, err := loadNodes(tx, tenantID, sessionID)
+		if err != nil {
+			return err
+		}
+		parentID, err := memory.ParentOf(nodes, messageID)
+		if err != nil {
+			return err
+		}
+		id, _ := parseID(parentID)
+		return setHead(tx, tenantID, sessionID, id)
+	})
+}
+
+// Checkout implements memory.Brancher.
+func (m *Memory) Checkout(ctx context.Context, sessionID, messageID string) error {
+	tenantID := memory.TenantFromContext(ctx)
+	id, ok := parseID(messageID)
+	if !ok {
+		return memory.ErrMessageNotFound
+	}
+
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if id != 0 {
+			var count int64
+			if err := tx.Model(&MessageModel{}).
+				Where("id = ? AND tenant_id = ? AND session_id = ?", id, tenantID, sessionID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return memory.ErrMessageNotFound
+			}
+		}
+		return setHead(tx, tenantID, sessionID, id)
+	})
+}
+
+// Branches implements memory.Brancher.
+func (m *Memory) Branches(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	nodes, err := loadNodes(m.db.WithContext(ctx), memory.TenantFromContext(ctx), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.Leaves(nodes), nil
+}
+
+// loadNodes reads every message of a session in write order.
+func loadNodes(tx *gorm.DB, tenantID, sessionID string) ([]memory.Node, error) {
+	var models []MessageModel
+	if err := tx.Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+		Order("seq asc, id asc").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make([]memory.Node, len(models))
+	for i, model := range models {
+		msg, err := toMessage(model)
+		if err != nil {
+			return nil, err
+		}
+
+		parentID := ""
+		if model.ParentID != nil {
+			parentID = formatID(*model.ParentID)
+		} else if i > 0 {
+			parentID = nodes[i-1].ID
+		}
+		nodes[i] = memory.Node{ID: formatID(model.ID), ParentID: parentID, Seq: model.Seq, Message: msg}
+	}
+	return nodes, nil
+}
+
+// head returns the last message of the active branch, or 0 if the branch is empty,
+// and the session's last sequence number.
+func head(tx *gorm.DB, tenantID, sessionID string) (uint, int64, error) {
+	var session SessionModel
+	if err := tx.Select(consts.ColHeadID, consts.ColLastSeq).
+		Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+		Limit(1).Find(&session).Error; err != nil {
+		return 0, 0, err
+	}
+	if session.HeadID != nil {
+		return *session.HeadID, session.LastSeq, nil
+	}
+
+	var last MessageModel
+	if err := tx.Select("id").
+		Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+		Order("seq desc, id desc").
+		Limit(1).Find(&last).Error; err != nil {
+		return 0, 0, err
+	}
+	return last.ID, session.LastSeq, nil
+}
+
+// advance moves the head and the last sequence number of a session, provided no other
+// save advanced it since lastSeq was read.
+func advance(tx *gorm.DB, tenantID, sessionID string, lastSeq, seq int64, headID uint) error {
+	result := tx.Model(&SessionModel{}).
+		Where("tenant_id = ? AND session_id = ? AND last_seq = ?", tenantID, sessionID, lastSeq).
+		Updates(map[string]interface{}{consts.ColHeadID: headID, consts.ColLastSeq: seq})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return memory.ErrSeqConflict
+	}
+	return nil
+}
+
+func setHead(tx *gorm.DB, tenantID, sessionID string, id uint) error {
+	return tx.Model(&SessionModel{}).
+		Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+		Update(consts.ColHeadID, id).Error
+}
+
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func parseID(s string) (uint, bool) {
+	if s == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
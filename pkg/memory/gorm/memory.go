@@ -4,21 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
 	"github.com/barekit/talos/pkg/memory/consts"
 	"gorm.io/gorm"
 )
 
 // Memory implements Memory using GORM.
 type Memory struct {
-	db *gorm.DB
+	db        *gorm.DB
+	retention time.Duration
+	interval  time.Duration
+	stop      func()
+}
+
+// Option configures a Memory.
+type Option func(*Memory)
+
+// WithRetention deletes sessions that have not been written to for retention,
+// checking every interval. A zero interval uses memory.StartSweeper's default.
+func WithRetention(retention, interval time.Duration) Option {
+	return func(m *Memory) {
+		m.retention = retention
+		m.interval = interval
+	}
 }
 
 // MessageModel represents the database schema for a message.
 type MessageModel struct {
 	gorm.Model
-	SessionID  string `gorm:"index"`
+	TenantID  string `gorm:"index;size:191;not null;default:''"`
+	SessionID string `gorm:"index"`
+	// Seq orders the messages of a session. Rows written before sequence numbers have 0
+	// and keep their insertion order ahead of newer rows.
+	Seq int64 `gorm:"not null;default:0"`
+	// ParentID is the previous message on the branch, 0 for the first message.
+	// Rows written before branching support have no parent and follow the previous row.
+	ParentID   *uint
 	Role       string
 	Content    string
 	ToolCalls  []byte `gorm:"type:json"` // Store as JSON bytes
@@ -31,60 +55,142 @@ func (MessageModel) TableName() string {
 }
 
 // New creates a new Memory.
-func New(db *gorm.DB) (*Memory, error) {
-	if err := db.AutoMigrate(&MessageModel{}); err != nil {
+func New(db *gorm.DB, opts ...Option) (*Memory, error) {
+	if err := db.AutoMigrate(&MessageModel{}, &SessionModel{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
-	return &Memory{db: db}, nil
+	if err := backfillSessions(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill sessions: %w", err)
+	}
+
+	m := &Memory{db: db}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.retention > 0 {
+		m.stop = memory.StartSweeper(m, m.retention, m.interval)
+	}
+	return m, nil
+}
+
+// Close stops the retention sweeper. The database connection is left open.
+func (m *Memory) Close() error {
+	if m.stop != nil {
+		m.stop()
+		m.stop = nil
+	}
+	return nil
+}
+
+// Sweep implements memory.Sweeper. Expired sessions and their messages are deleted permanently,
+// as are messages older than the cutoff that have no session record.
+func (m *Memory) Sweep(ctx context.Context, cutoff time.Time) (int64, error) {
+	var deleted int64
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&SessionModel{}).Select("1").Where(
+			fmt.Sprintf("%[1]s.tenant_id = %[2]s.tenant_id AND %[1]s.session_id = %[2]s.session_id AND %[1]s.updated_at < ?",
+				consts.TableNameSessions, consts.TableNameMessages),
+			cutoff,
+		)
+		result := tx.Unscoped().Where("EXISTS (?)", expired).Delete(&MessageModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		// Messages without a session row predate session records; they expire on their own age.
+		session := tx.Model(&SessionModel{}).Select("1").Where(
+			fmt.Sprintf("%[1]s.tenant_id = %[2]s.tenant_id AND %[1]s.session_id = %[2]s.session_id",
+				consts.TableNameSessions, consts.TableNameMessages),
+		)
+		result = tx.Unscoped().Where("updated_at < ? AND NOT EXISTS (?)", cutoff, session).Delete(&MessageModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted += result.RowsAffected
+
+		return tx.Where("updated_at < ?", cutoff).Delete(&SessionModel{}).Error
+	})
+	return deleted, err
 }
 
 // Save saves a message to the database.
 func (m *Memory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
-	var toolCallsJSON []byte
-	if len(msg.ToolCalls) > 0 {
-		b, err := json.Marshal(msg.ToolCalls)
-		if err != nil {
-			return fmt.Errorf("failed to marshal tool calls: %w", err)
+	return m.SaveBatch(ctx, sessionID, []llm.Message{msg})
+}
+
+// SaveBatch implements memory.BatchSaver. The messages are appended in one transaction.
+func (m *Memory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	tenantID := memory.TenantFromContext(ctx)
+	models := make([]MessageModel, len(msgs))
+	for i, msg := range msgs {
+		var toolCallsJSON []byte
+		if len(msg.ToolCalls) > 0 {
+			b, err := json.Marshal(msg.ToolCalls)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tool calls: %w", err)
+			}
+			toolCallsJSON = b
 		}
-		toolCallsJSON = b
-	}
 
-	model := MessageModel{
-		SessionID:  sessionID,
-		Role:       string(msg.Role),
-		Content:    msg.Content,
-		ToolCalls:  toolCallsJSON,
-		ToolCallID: msg.ToolCallID,
+		models[i] = MessageModel{
+			TenantID:   tenantID,
+			SessionID:  sessionID,
+			Role:       string(msg.Role),
+			Content:    msg.Content,
+			ToolCalls:  toolCallsJSON,
+			ToolCallID: msg.ToolCallID,
+		}
 	}
 
-	return m.db.WithContext(ctx).Create(&model).Error
+	return memory.RetrySeq(ctx, func() error {
+		return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := touch(tx, tenantID, sessionID, time.Now()); err != nil {
+				return err
+			}
+			parentID, lastSeq, err := head(tx, tenantID, sessionID)
+			if err != nil {
+				return err
+			}
+			// Each message needs the ID of the previous one, so they are inserted in order.
+			for i := range models {
+				parent := parentID
+				models[i].ID = 0
+				models[i].ParentID = &parent
+				models[i].Seq = lastSeq + int64(i) + 1
+				if err := tx.Create(&models[i]).Error; err != nil {
+					return err
+				}
+				parentID = models[i].ID
+			}
+			return advance(tx, tenantID, sessionID, lastSeq, lastSeq+int64(len(models)), parentID)
+		})
+	})
 }
 
-// Load loads messages from the database.
+// Load loads the active branch from the database.
 func (m *Memory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
-	var models []MessageModel
-	if err := m.db.WithContext(ctx).Where("session_id = ?", sessionID).Order("created_at asc").Find(&models).Error; err != nil {
+	path, err := m.History(ctx, sessionID)
+	if err != nil {
 		return nil, err
 	}
+	return memory.Messages(path), nil
+}
 
-	messages := make([]llm.Message, len(models))
-	for i, model := range models {
-		msg := llm.Message{
-			Role:       llm.Role(model.Role),
-			Content:    model.Content,
-			ToolCallID: model.ToolCallID,
-		}
+func toMessage(model MessageModel) (llm.Message, error) {
+	msg := llm.Message{
+		Role:       llm.Role(model.Role),
+		Content:    model.Content,
+		ToolCallID: model.ToolCallID,
+	}
 
-		if len(model.ToolCalls) > 0 {
-			var toolCalls []llm.ToolCall
-			if err := json.Unmarshal(model.ToolCalls, &toolCalls); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tool calls for msg %d: %w", model.ID, err)
-			}
-			msg.ToolCalls = toolCalls
+	if len(model.ToolCalls) > 0 {
+		var toolCalls []llm.ToolCall
+		if err := json.Unmarshal(model.ToolCalls, &toolCalls); err != nil {
+			return msg, fmt.Errorf("failed to unmarshal tool calls for msg %d: %w", model.ID, err)
 		}
-
-		messages[i] = msg
+		msg.ToolCalls = toolCalls
 	}
 
-	return messages, nil
+	return msg, nil
 }
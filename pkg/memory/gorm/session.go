@@ -0,0 +1,198 @@
+package gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionModel represents the database schema for a session record.
+type SessionModel struct {
+	TenantID  string `gorm:"primaryKey;size:191"`
+	SessionID string `gorm:"primaryKey;size:191"`
+	UserID    string `gorm:"index;size:191"`
+	Title     string
+	// Tags are stored as ",a,b," so a single tag can be matched with LIKE.
+	Tags       string
+	Attributes []byte    `gorm:"type:json"`
+	CreatedAt  time.Time `gorm:"autoCreateTime:false"`
+	UpdatedAt  time.Time `gorm:"index;autoUpdateTime:false"`
+	// HeadID is the last message of the active branch, 0 before the first message.
+	// It is unset for sessions written before branching support, whose head is their last message.
+	HeadID *uint
+	// LastSeq is the sequence number of the last message written to the session.
+	LastSeq int64 `gorm:"not null;default:0"`
+}
+
+// TableName overrides the table name.
+func (SessionModel) TableName() string {
+	return consts.TableNameSessions
+}
+
+// backfillSessions creates the session records of sessions written before session
+// records existed, so they are listed, swept and migrated like any other session.
+func backfillSessions(db *gorm.DB) error {
+	return db.Exec(fmt.Sprintf(`
+		INSERT INTO %[1]s (tenant_id, session_id, user_id, title, tags, created_at, updated_at)
+		SELECT m.tenant_id, m.session_id, '', '', '', MIN(m.created_at), MAX(m.created_at)
+		FROM %[2]s m
+		WHERE m.deleted_at IS NULL AND NOT EXISTS (
+			SELECT 1 FROM %[1]s s WHERE s.tenant_id = m.tenant_id AND s.session_id = m.session_id
+		)
+		GROUP BY m.tenant_id, m.session_id`,
+		consts.TableNameSessions, consts.TableNameMessages,
+	)).Error
+}
+
+// touch creates the session record if needed and bumps its activity time.
+func touch(tx *gorm.DB, tenantID, sessionID string, now time.Time) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: consts.ColTenantID}, {Name: consts.ColSessionID}},
+		DoUpdates: clause.Assignments(map[string]interface{}{consts.ColUpdatedAt: now}),
+	}).Create(&SessionModel{
+		TenantID:  tenantID,
+		SessionID: sessionID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}).Error
+}
+
+// SaveSession implements memory.SessionStore.
+func (m *Memory) SaveSession(ctx context.Context, session memory.Session) error {
+	if err := memory.PrepareSession(ctx, &session); err != nil {
+		return err
+	}
+
+	var attributes []byte
+	if len(session.Attributes) > 0 {
+		b, err := json.Marshal(session.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+		attributes = b
+	}
+
+	now := time.Now()
+	model := SessionModel{
+		TenantID:   session.TenantID,
+		SessionID:  session.ID,
+		UserID:     session.UserID,
+		Title:      session.Title,
+		Tags:       encodeTags(session.Tags),
+		Attributes: attributes,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	return m.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: consts.ColTenantID}, {Name: consts.ColSessionID}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			consts.ColUserID, consts.ColTitle, consts.ColTags, consts.ColAttributes, consts.ColUpdatedAt,
+		}),
+	}).Create(&model).Error
+}
+
+// likeEscaper escapes LIKE wildcards for the ESCAPE '!' clause. "[" is a wildcard in SQL Server.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![")
+
+// GetSession implements memory.SessionStore.
+func (m *Memory) GetSession(ctx context.Context, sessionID string) (*memory.Session, error) {
+	var model SessionModel
+	err := m.db.WithContext(ctx).
+		Where("tenant_id = ? AND session_id = ?", memory.TenantFromContext(ctx), sessionID).
+		Take(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, memory.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toSession(model)
+}
+
+// ListSessions implements memory.SessionStore.
+func (m *Memory) ListSessions(ctx context.Context, query memory.SessionQuery) ([]memory.Session, error) {
+	db := m.db.WithContext(ctx).Where("tenant_id = ?", memory.TenantFromContext(ctx))
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Tag != "" {
+		db = db.Where("tags LIKE ? ESCAPE '!'", "%,"+likeEscaper.Replace(query.Tag)+",%")
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	var models []SessionModel
+	if err := db.Order("updated_at desc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]memory.Session, len(models))
+	for i, model := range models {
+		s, err := toSession(model)
+		if err != nil {
+			return nil, err
+		}
+		sessions[i] = *s
+	}
+	return sessions, nil
+}
+
+// DeleteSession implements memory.SessionStore. Messages are deleted permanently.
+func (m *Memory) DeleteSession(ctx context.Context, sessionID string) error {
+	tenantID := memory.TenantFromContext(ctx)
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+			Delete(&MessageModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ? AND session_id = ?", tenantID, sessionID).
+			Delete(&SessionModel{}).Error
+	})
+}
+
+func toSession(model SessionModel) (*memory.Session, error) {
+	s := &memory.Session{
+		ID:        model.SessionID,
+		TenantID:  model.TenantID,
+		UserID:    model.UserID,
+		Title:     model.Title,
+		Tags:      decodeTags(model.Tags),
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+	if len(model.Attributes) > 0 {
+		if err := json.Unmarshal(model.Attributes, &s.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attributes of session %s: %w", model.SessionID, err)
+		}
+	}
+	return s, nil
+}
+
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+func decodeTags(s string) []string {
+	s = strings.Trim(s, ",")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -2,42 +2,246 @@ package inmemory
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
 )
 
+func init() {
+	memory.Register(memory.TypeInMemory, func(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+		return New(), nil
+	}, "inmemory", "memory")
+}
+
+// sessionKey identifies a session within a tenant.
+type sessionKey struct {
+	tenantID  string
+	sessionID string
+}
+
+func keyFor(ctx context.Context, sessionID string) sessionKey {
+	return sessionKey{tenantID: memory.TenantFromContext(ctx), sessionID: sessionID}
+}
+
+// thread is the message tree of a session.
+type thread struct {
+	nodes []memory.Node
+	head  string
+}
+
 // InMemory implements Memory using a map.
 type InMemory struct {
 	mu       sync.RWMutex
-	messages map[string][]llm.Message
+	threads  map[sessionKey]*thread
+	sessions map[sessionKey]*memory.Session
 }
 
 // New creates a new InMemory adapter.
 func New() *InMemory {
 	return &InMemory{
-		messages: make(map[string][]llm.Message),
+		threads:  make(map[sessionKey]*thread),
+		sessions: make(map[sessionKey]*memory.Session),
 	}
 }
 
 // Save saves a message to the in-memory store.
 func (m *InMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+	return m.SaveBatch(ctx, sessionID, []llm.Message{msg})
+}
+
+// SaveBatch implements memory.BatchSaver.
+func (m *InMemory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.messages[sessionID] = append(m.messages[sessionID], msg)
+	key := keyFor(ctx, sessionID)
+	m.touch(key, time.Now())
+
+	t, ok := m.threads[key]
+	if !ok {
+		t = &thread{}
+		m.threads[key] = t
+	}
+	// The mutex serializes writers, so the sequence number is simply the node's position.
+	for _, msg := range msgs {
+		seq := len(t.nodes) + 1
+		node := memory.Node{ID: strconv.Itoa(seq), ParentID: t.head, Seq: int64(seq), Message: msg}
+		t.nodes = append(t.nodes, node)
+		t.head = node.ID
+	}
 	return nil
 }
 
-// Load loads messages from the in-memory store.
+// Load loads the active branch from the in-memory store.
 func (m *InMemory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
+	path, err := m.History(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.Messages(path), nil
+}
+
+// History implements memory.Brancher.
+func (m *InMemory) History(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// Return a copy to avoid race conditions if the caller modifies the slice
-	msgs := m.messages[sessionID]
-	result := make([]llm.Message, len(msgs))
-	copy(result, msgs)
+	t, ok := m.threads[keyFor(ctx, sessionID)]
+	if !ok {
+		return []memory.Node{}, nil
+	}
+	// BranchPath builds a new slice, so callers cannot modify the stored nodes.
+	return memory.BranchPath(t.nodes, t.head)
+}
+
+// Fork implements memory.Brancher.
+func (m *InMemory) Fork(ctx context.Context, sessionID, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// An unknown session has no messages, so no message can be found in it.
+	t, ok := m.threads[keyFor(ctx, sessionID)]
+	if !ok {
+		return fmt.Errorf("%w: %s", memory.ErrMessageNotFound, messageID)
+	}
+	parentID, err := memory.ParentOf(t.nodes, messageID)
+	if err != nil {
+		return err
+	}
+	t.head = parentID
+	return nil
+}
 
-	return result, nil
+// Checkout implements memory.Brancher.
+func (m *InMemory) Checkout(ctx context.Context, sessionID, messageID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.threads[keyFor(ctx, sessionID)]
+	if !ok {
+		if messageID == "" {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", memory.ErrMessageNotFound, messageID)
+	}
+	if messageID != "" {
+		if _, err := memory.ParentOf(t.nodes, messageID); err != nil {
+			return err
+		}
+	}
+	t.head = messageID
+	return nil
+}
+
+// Branches implements memory.Brancher.
+func (m *InMemory) Branches(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.threads[keyFor(ctx, sessionID)]
+	if !ok {
+		return nil, nil
+	}
+	return memory.Leaves(t.nodes), nil
+}
+
+// touch creates the session record if needed and bumps its activity time.
+func (m *InMemory) touch(key sessionKey, now time.Time) *memory.Session {
+	s, ok := m.sessions[key]
+	if !ok {
+		s = &memory.Session{ID: key.sessionID, TenantID: key.tenantID, CreatedAt: now}
+		m.sessions[key] = s
+	}
+	s.UpdatedAt = now
+	return s
+}
+
+// SaveSession implements memory.SessionStore.
+func (m *InMemory) SaveSession(ctx context.Context, session memory.Session) error {
+	if err := memory.PrepareSession(ctx, &session); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.touch(keyFor(ctx, session.ID), time.Now())
+	s.UserID = session.UserID
+	s.Title = session.Title
+	s.Tags = append([]string(nil), session.Tags...)
+	s.Attributes = copyAttributes(session.Attributes)
+	return nil
+}
+
+// GetSession implements memory.SessionStore.
+func (m *InMemory) GetSession(ctx context.Context, sessionID string) (*memory.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[keyFor(ctx, sessionID)]
+	if !ok {
+		return nil, memory.ErrSessionNotFound
+	}
+	result := copySession(*s)
+	return &result, nil
+}
+
+// ListSessions implements memory.SessionStore.
+func (m *InMemory) ListSessions(ctx context.Context, query memory.SessionQuery) ([]memory.Session, error) {
+	tenantID := memory.TenantFromContext(ctx)
+
+	m.mu.RLock()
+	var sessions []memory.Session
+	for key, s := range m.sessions {
+		if key.tenantID == tenantID && query.Matches(*s) {
+			sessions = append(sessions, copySession(*s))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return query.Page(sessions), nil
+}
+
+// DeleteSession implements memory.SessionStore.
+func (m *InMemory) DeleteSession(ctx context.Context, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := keyFor(ctx, sessionID)
+	delete(m.sessions, key)
+	delete(m.threads, key)
+	return nil
+}
+
+func copySession(s memory.Session) memory.Session {
+	s.Tags = append([]string(nil), s.Tags...)
+	s.Attributes = copyAttributes(s.Attributes)
+	return s
+}
+
+func copyAttributes(attrs map[string]string) map[string]string {
+	if attrs == nil {
+		return nil
+	}
+	result := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		result[k] = v
+	}
+	return result
 }
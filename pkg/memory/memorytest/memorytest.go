@@ -0,0 +1,569 @@
+// Package memorytest provides a conformance suite for memory.Memory adapters.
+package memorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/google/uuid"
+)
+
+// Factory returns the Memory under test. It may be shared between subtests:
+// every subtest uses its own random session IDs.
+type Factory func(t *testing.T) memory.Memory
+
+// Run checks that the Memory returned by factory behaves like every adapter should.
+func Run(t *testing.T, factory Factory) {
+	t.Run("EmptySession", func(t *testing.T) { testEmptySession(t, factory(t)) })
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, factory(t)) })
+	t.Run("SessionIsolation", func(t *testing.T) { testSessionIsolation(t, factory(t)) })
+	t.Run("ToolCallRoundTrip", func(t *testing.T) { testToolCallRoundTrip(t, factory(t)) })
+	t.Run("ConcurrentWriters", func(t *testing.T) { testConcurrentWriters(t, factory(t)) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, factory(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, factory(t)) })
+	t.Run("Sessions", func(t *testing.T) {
+		store, ok := factory(t).(memory.SessionStore)
+		if !ok {
+			t.Skip("adapter does not implement memory.SessionStore")
+		}
+		testSessions(t, store)
+	})
+	t.Run("Branching", func(t *testing.T) {
+		brancher, ok := factory(t).(memory.Brancher)
+		if !ok {
+			t.Skip("adapter does not implement memory.Brancher")
+		}
+		testBranching(t, brancher)
+	})
+	t.Run("Sequence", func(t *testing.T) {
+		brancher, ok := factory(t).(memory.Brancher)
+		if !ok {
+			t.Skip("adapter does not implement memory.Brancher")
+		}
+		testSequence(t, brancher)
+	})
+	t.Run("Batch", func(t *testing.T) {
+		saver, ok := factory(t).(memory.BatchSaver)
+		if !ok {
+			t.Skip("adapter does not implement memory.BatchSaver")
+		}
+		testBatch(t, saver)
+	})
+}
+
+func newSessionID() string {
+	return "memorytest-" + uuid.NewString()
+}
+
+func mustSave(t *testing.T, m memory.Memory, sessionID string, msg llm.Message) {
+	t.Helper()
+	if err := m.Save(context.Background(), sessionID, msg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+}
+
+func mustLoad(t *testing.T, m memory.Memory, sessionID string) []llm.Message {
+	t.Helper()
+	msgs, err := m.Load(context.Background(), sessionID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return msgs
+}
+
+func testEmptySession(t *testing.T, m memory.Memory) {
+	if msgs := mustLoad(t, m, newSessionID()); len(msgs) != 0 {
+		t.Errorf("Expected no messages for an unknown session, got %d", len(msgs))
+	}
+}
+
+func testOrdering(t *testing.T, m memory.Memory) {
+	sessionID := newSessionID()
+	const n = 50
+	for i := 0; i < n; i++ {
+		role := llm.RoleUser
+		if i%2 == 1 {
+			role = llm.RoleAssistant
+		}
+		mustSave(t, m, sessionID, llm.Message{Role: role, Content: fmt.Sprintf("message %d", i)})
+	}
+
+	msgs := mustLoad(t, m, sessionID)
+	if len(msgs) != n {
+		t.Fatalf("Expected %d messages, got %d", n, len(msgs))
+	}
+	for i, msg := range msgs {
+		if want := fmt.Sprintf("message %d", i); msg.Content != want {
+			t.Fatalf("Message %d out of order: got %q, want %q", i, msg.Content, want)
+		}
+	}
+}
+
+func testSessionIsolation(t *testing.T, m memory.Memory) {
+	a, b := newSessionID(), newSessionID()
+	mustSave(t, m, a, llm.Message{Role: llm.RoleUser, Content: "for a"})
+	mustSave(t, m, b, llm.Message{Role: llm.RoleUser, Content: "for b"})
+	mustSave(t, m, a, llm.Message{Role: llm.RoleAssistant, Content: "also for a"})
+
+	msgsA := mustLoad(t, m, a)
+	if len(msgsA) != 2 || msgsA[0].Content != "for a" || msgsA[1].Content != "also for a" {
+		t.Errorf("Unexpected messages for session a: %+v", msgsA)
+	}
+	msgsB := mustLoad(t, m, b)
+	if len(msgsB) != 1 || msgsB[0].Content != "for b" {
+		t.Errorf("Unexpected messages for session b: %+v", msgsB)
+	}
+}
+
+func testToolCallRoundTrip(t *testing.T, m memory.Memory) {
+	sessionID := newSessionID()
+	call := llm.ToolCall{
+		ID:   "call_1",
+		Type: "function",
+		Function: llm.Function{
+			Name:      "Add",
+			Arguments: `{"a":1,"b":2}`,
+		},
+	}
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: "What is 1 + 2?"})
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{call}})
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleTool, Content: "3", ToolCallID: "call_1"})
+
+	msgs := mustLoad(t, m, sessionID)
+	if len(msgs) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(msgs))
+	}
+
+	assistant := msgs[1]
+	if assistant.Role != llm.RoleAssistant || len(assistant.ToolCalls) != 1 {
+		t.Fatalf("Expected assistant message with one tool call, got %+v", assistant)
+	}
+	if got := assistant.ToolCalls[0]; got != call {
+		t.Errorf("Tool call did not round-trip: got %+v, want %+v", got, call)
+	}
+
+	result := msgs[2]
+	if result.Role != llm.RoleTool || result.ToolCallID != "call_1" || result.Content != "3" {
+		t.Errorf("Tool result did not round-trip: %+v", result)
+	}
+}
+
+func testConcurrentWriters(t *testing.T, m memory.Memory) {
+	sessionID := newSessionID()
+	const writers, perWriter = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				msg := llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("%d/%d", w, i)}
+				if err := m.Save(context.Background(), sessionID, msg); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Concurrent Save failed: %v", err)
+	}
+
+	msgs := mustLoad(t, m, sessionID)
+	if len(msgs) != writers*perWriter {
+		t.Fatalf("Expected %d messages, got %d", writers*perWriter, len(msgs))
+	}
+
+	// Writers interleave, but each writer's own messages must keep their order.
+	next := make(map[int]int)
+	for _, msg := range msgs {
+		var w, i int
+		if _, err := fmt.Sscanf(msg.Content, "%d/%d", &w, &i); err != nil {
+			t.Fatalf("Unexpected message %q", msg.Content)
+		}
+		if i != next[w] {
+			t.Fatalf("Writer %d: got message %d, want %d", w, i, next[w])
+		}
+		next[w]++
+	}
+}
+
+func testContextCancellation(t *testing.T, m memory.Memory) {
+	sessionID := newSessionID()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := m.Save(ctx, sessionID, llm.Message{Role: llm.RoleUser, Content: "cancelled"}); err == nil {
+		t.Errorf("Expected Save to fail with a cancelled context")
+	}
+	if _, err := m.Load(ctx, sessionID); err == nil {
+		t.Errorf("Expected Load to fail with a cancelled context")
+	}
+	if msgs := mustLoad(t, m, sessionID); len(msgs) != 0 {
+		t.Errorf("Expected cancelled Save to store nothing, got %d messages", len(msgs))
+	}
+}
+
+func testTenantIsolation(t *testing.T, m memory.Memory) {
+	sessionID := newSessionID()
+	ctxA := memory.WithTenant(context.Background(), "tenant-a")
+	ctxB := memory.WithTenant(context.Background(), "tenant-b")
+
+	if err := m.Save(ctxA, sessionID, llm.Message{Role: llm.RoleUser, Content: "from a"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := m.Save(ctxB, sessionID, llm.Message{Role: llm.RoleUser, Content: "from b"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	msgs, err := m.Load(ctxA, sessionID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Content != "from a" {
+		t.Errorf("Tenant a sees %+v", msgs)
+	}
+	if msgs := mustLoad(t, m, sessionID); len(msgs) != 0 {
+		t.Errorf("Default tenant sees %d messages of other tenants", len(msgs))
+	}
+}
+
+func testSessions(t *testing.T, store memory.SessionStore) {
+	m := store.(memory.Memory)
+	ctx := memory.WithTenant(context.Background(), "tenant-"+uuid.NewString())
+	userID := "user-" + uuid.NewString()
+	older, newer, other := newSessionID(), newSessionID(), newSessionID()
+
+	// Saving a message creates the session record.
+	if err := m.Save(ctx, older, llm.Message{Role: llm.RoleUser, Content: "hello"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s, err := store.GetSession(ctx, older)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if s.ID != older || s.CreatedAt.IsZero() || s.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected session record: %+v", s)
+	}
+
+	save := func(id, title string, tags ...string) {
+		t.Helper()
+		err := store.SaveSession(ctx, memory.Session{
+			ID:         id,
+			UserID:     userID,
+			Title:      title,
+			Tags:       tags,
+			Attributes: map[string]string{"channel": "web"},
+		})
+		if err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	save(older, "Older", "billing")
+	save(newer, "Newer", "billing", "urgent")
+	if err := store.SaveSession(ctx, memory.Session{ID: other, UserID: "someone-else"}); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	s, err = store.GetSession(ctx, newer)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if s.Title != "Newer" || s.UserID != userID || len(s.Tags) != 2 || s.Attributes["channel"] != "web" {
+		t.Errorf("Session fields did not round-trip: %+v", s)
+	}
+
+	// Activity on the older session moves it to the top.
+	time.Sleep(5 * time.Millisecond)
+	if err := m.Save(ctx, older, llm.Message{Role: llm.RoleUser, Content: "again"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sessions, err := store.ListSessions(ctx, memory.SessionQuery{UserID: userID})
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != older || sessions[1].ID != newer {
+		t.Errorf("Expected sessions ordered by activity, got %+v", sessions)
+	}
+
+	sessions, _ = store.ListSessions(ctx, memory.SessionQuery{UserID: userID, Tag: "urgent"})
+	if len(sessions) != 1 || sessions[0].ID != newer {
+		t.Errorf("Expected tag filter to match newer only, got %+v", sessions)
+	}
+	// Tag values are matched literally, not as patterns.
+	wildcard := "wildcard-" + uuid.NewString()
+	if err := store.SaveSession(ctx, memory.Session{ID: wildcard, UserID: "wildcard-user", Tags: []string{"axb"}}); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	for _, tag := range []string{"a_b", "a%b", "%"} {
+		if sessions, _ := store.ListSessions(ctx, memory.SessionQuery{UserID: "wildcard-user", Tag: tag}); len(sessions) != 0 {
+			t.Errorf("Expected tag %q not to match axb, got %+v", tag, sessions)
+		}
+	}
+	sessions, _ = store.ListSessions(ctx, memory.SessionQuery{UserID: userID, Limit: 1, Offset: 1})
+	if len(sessions) != 1 || sessions[0].ID != newer {
+		t.Errorf("Expected second page to hold newer, got %+v", sessions)
+	}
+
+	// Other tenants see nothing.
+	otherTenant := memory.WithTenant(context.Background(), "tenant-"+uuid.NewString())
+	if _, err := store.GetSession(otherTenant, older); !errors.Is(err, memory.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound from another tenant, got %v", err)
+	}
+	if sessions, _ := store.ListSessions(otherTenant, memory.SessionQuery{UserID: userID}); len(sessions) != 0 {
+		t.Errorf("Another tenant lists %d sessions", len(sessions))
+	}
+
+	if err := store.DeleteSession(ctx, older); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if _, err := store.GetSession(ctx, older); !errors.Is(err, memory.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound after delete, got %v", err)
+	}
+	if msgs, err := m.Load(ctx, older); err != nil || len(msgs) != 0 {
+		t.Errorf("Expected messages to be deleted, got %d (%v)", len(msgs), err)
+	}
+}
+
+func testBranching(t *testing.T, b memory.Brancher) {
+	m := b.(memory.Memory)
+	ctx := context.Background()
+	sessionID := newSessionID()
+
+	contents := func(msgs []llm.Message) []string {
+		result := make([]string, len(msgs))
+		for i, msg := range msgs {
+			result[i] = msg.Content
+		}
+		return result
+	}
+	expectBranch := func(want ...string) {
+		t.Helper()
+		got := contents(mustLoad(t, m, sessionID))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("Expected branch %v, got %v", want, got)
+		}
+	}
+
+	for _, content := range []string{"q1", "a1", "q2", "a2"} {
+		mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: content})
+	}
+
+	history, err := b.History(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 4 || history[0].ParentID != "" || history[3].ParentID != history[2].ID {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+
+	// Editing q2: fork at it and save the new prompt as a sibling.
+	if err := b.Fork(ctx, sessionID, history[2].ID); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	expectBranch("q1", "a1")
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: "q2 edited"})
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: "a2 edited"})
+	expectBranch("q1", "a1", "q2 edited", "a2 edited")
+
+	branches, err := b.Branches(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("Branches failed: %v", err)
+	}
+	if len(branches) != 2 || branches[0].ID != history[3].ID || branches[1].Message.Content != "a2 edited" {
+		t.Fatalf("Unexpected branches: %+v", branches)
+	}
+
+	// The original branch is still there.
+	if err := b.Checkout(ctx, sessionID, history[3].ID); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	expectBranch("q1", "a1", "q2", "a2")
+
+	// Forking at the first message starts over.
+	if err := b.Fork(ctx, sessionID, history[0].ID); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	expectBranch()
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: "fresh"})
+	expectBranch("fresh")
+
+	if err := b.Checkout(ctx, sessionID, "does-not-exist"); !errors.Is(err, memory.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+	if err := b.Fork(ctx, sessionID, "does-not-exist"); !errors.Is(err, memory.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+
+	// Unknown sessions behave like empty ones in every adapter.
+	unknown := "unknown-" + uuid.NewString()
+	if err := b.Fork(ctx, unknown, history[3].ID); !errors.Is(err, memory.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound forking an unknown session, got %v", err)
+	}
+	if err := b.Checkout(ctx, unknown, history[3].ID); !errors.Is(err, memory.ErrMessageNotFound) {
+		t.Errorf("Expected ErrMessageNotFound checking out an unknown session, got %v", err)
+	}
+	if err := b.Checkout(ctx, unknown, ""); err != nil {
+		t.Errorf("Expected checking out the root of an unknown session to succeed, got %v", err)
+	}
+
+	// Branches are scoped to the tenant.
+	if err := b.Checkout(memory.WithTenant(ctx, "other-"+uuid.NewString()), sessionID, history[3].ID); err == nil {
+		t.Error("Expected Checkout from another tenant to fail")
+	}
+	expectBranch("fresh")
+}
+
+func testSequence(t *testing.T, b memory.Brancher) {
+	m := b.(memory.Memory)
+	ctx := context.Background()
+	sessionID := newSessionID()
+
+	// Messages saved back to back share timestamps on most clocks; sequence numbers still order them.
+	for i := 0; i < 10; i++ {
+		mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("message %d", i)})
+	}
+	history, err := b.History(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	for i, n := range history {
+		if n.Seq != int64(i+1) {
+			t.Fatalf("Message %d: got seq %d, want %d", i, n.Seq, i+1)
+		}
+	}
+
+	// Sequence numbers keep growing across branches.
+	if err := b.Fork(ctx, sessionID, history[5].ID); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	mustSave(t, m, sessionID, llm.Message{Role: llm.RoleUser, Content: "edited"})
+	history, err = b.History(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if last := history[len(history)-1]; last.Message.Content != "edited" || last.Seq != 11 {
+		t.Errorf("Expected the edited message with seq 11, got %+v", last)
+	}
+}
+
+func testBatch(t *testing.T, b memory.BatchSaver) {
+	m := b.(memory.Memory)
+	ctx := context.Background()
+	sessionID := newSessionID()
+
+	if err := b.SaveBatch(ctx, sessionID, nil); err != nil {
+		t.Fatalf("Empty SaveBatch failed: %v", err)
+	}
+	if msgs := mustLoad(t, m, sessionID); len(msgs) != 0 {
+		t.Fatalf("Expected no messages after an empty batch, got %d", len(msgs))
+	}
+
+	// Concurrent batches may interleave with each other but never split.
+	const writers, batchSize = 8, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			batch := make([]llm.Message, batchSize)
+			for i := range batch {
+				batch[i] = llm.Message{Role: llm.RoleTool, Content: fmt.Sprintf("%d/%d", w, i), ToolCallID: fmt.Sprintf("call_%d", i)}
+			}
+			if err := b.SaveBatch(ctx, sessionID, batch); err != nil {
+				errs <- err
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Concurrent SaveBatch failed: %v", err)
+	}
+
+	msgs := mustLoad(t, m, sessionID)
+	if len(msgs) != writers*batchSize {
+		t.Fatalf("Expected %d messages, got %d", writers*batchSize, len(msgs))
+	}
+	for start := 0; start < len(msgs); start += batchSize {
+		var writer int
+		for i, msg := range msgs[start : start+batchSize] {
+			var w, j int
+			if _, err := fmt.Sscanf(msg.Content, "%d/%d", &w, &j); err != nil {
+				t.Fatalf("Unexpected message %q", msg.Content)
+			}
+			if i == 0 {
+				writer = w
+			}
+			if w != writer || j != i {
+				t.Fatalf("Batch split at message %d: got %q", start+i, msg.Content)
+			}
+		}
+	}
+
+	if brancher, ok := b.(memory.Brancher); ok {
+		testFailedBatch(t, b, brancher)
+	}
+}
+
+// testFailedBatch checks that a batch interrupted by its context is either saved
+// completely or leaves no trace, in particular no branch beside the active one.
+func testFailedBatch(t *testing.T, b memory.BatchSaver, brancher memory.Brancher) {
+	sessionID := newSessionID()
+	mustSave(t, b.(memory.Memory), sessionID, llm.Message{Role: llm.RoleUser, Content: "start"})
+
+	saved := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		// Deadlines from already expired to a few milliseconds interrupt the save at different points.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i)*250*time.Microsecond)
+		batch := []llm.Message{
+			{Role: llm.RoleUser, Content: fmt.Sprintf("%d/0", i)},
+			{Role: llm.RoleAssistant, Content: fmt.Sprintf("%d/1", i)},
+		}
+		saved[i] = b.SaveBatch(ctx, sessionID, batch) == nil
+		cancel()
+	}
+
+	ctx := context.Background()
+	branches, err := brancher.Branches(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("Branches failed: %v", err)
+	}
+	if len(branches) != 1 {
+		t.Fatalf("Expected failed batches to leave no branch, got %d leaves", len(branches))
+	}
+
+	history, err := brancher.History(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	found := make(map[int]int)
+	for _, node := range history[1:] {
+		var i, j int
+		if _, err := fmt.Sscanf(node.Message.Content, "%d/%d", &i, &j); err != nil {
+			t.Fatalf("Unexpected message %q", node.Message.Content)
+		}
+		found[i]++
+	}
+	for i, ok := range saved {
+		if ok && found[i] != 2 {
+			t.Errorf("Batch %d was saved but %d of its messages are on the branch", i, found[i])
+		}
+		if !ok && found[i] != 0 && found[i] != 2 {
+			t.Errorf("Failed batch %d was saved partially: %d messages", i, found[i])
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// append inserts docs as a chain below the session head, numbers them after the session's
+// last sequence number and then moves the head. Without transactions the head and sequence
+// number are moved with a compare-and-set; if another save got there first, the docs are
+// re-parented and renumbered and the move retried. If the move never succeeds the docs are deleted.
+func (m *MongoMemory) append(ctx context.Context, sessionID string, docs []MessageDoc) error {
+	ids := make([]primitive.ObjectID, len(docs))
+	for i := range docs {
+		ids[i] = primitive.NewObjectID()
+		docs[i].ID = ids[i]
+		if i > 0 {
+			parentID := ids[i-1].Hex()
+			docs[i].ParentID = &parentID
+		}
+	}
+	last := ids[len(ids)-1]
+
+	inserted := false
+	err := memory.RetrySeq(ctx, func() error {
+		state, err := m.head(ctx, sessionID, ids...)
+		if err != nil {
+			return err
+		}
+		docs[0].ParentID = &state.id
+		for i := range docs {
+			docs[i].Seq = state.lastSeq + int64(i) + 1
+		}
+
+		if !inserted {
+			batch := make([]interface{}, len(docs))
+			for i := range docs {
+				batch[i] = docs[i]
+			}
+			if _, err := m.collection.InsertMany(ctx, batch); err != nil {
+				return err
+			}
+			inserted = true
+		} else if err := m.renumber(ctx, docs); err != nil {
+			return err
+		}
+
+		expectedHead := bson.M{"$exists": false}
+		if state.isSet {
+			expectedHead = bson.M{"$eq": state.id}
+		}
+		// Sessions written before sequence numbers have no last_seq, which matches null.
+		expectedSeq := bson.M{"$eq": state.lastSeq}
+		if state.lastSeq == 0 {
+			expectedSeq = bson.M{"$in": bson.A{nil, 0}}
+		}
+		filter := sessionFilter(ctx, sessionID)
+		filter[consts.ColHeadID] = expectedHead
+		filter[consts.ColLastSeq] = expectedSeq
+
+		result, err := m.sessions.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+			consts.ColHeadID:  last.Hex(),
+			consts.ColLastSeq: docs[len(docs)-1].Seq,
+		}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return memory.ErrSeqConflict
+		}
+		return nil
+	})
+	if err != nil && inserted {
+		// The docs never became part of the session; left behind they would show up as a branch.
+		// The cleanup must also run when ctx was cancelled.
+		filter := bson.M{"_id": bson.M{"$in": ids}}
+		if _, cleanupErr := m.collection.DeleteMany(context.WithoutCancel(ctx), filter); cleanupErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to delete unsaved messages: %w", cleanupErr))
+		}
+	}
+	return err
+}
+
+// renumber writes the parent of the first doc and the sequence numbers of all docs
+// after a lost compare-and-set.
+func (m *MongoMemory) renumber(ctx context.Context, docs []MessageDoc) error {
+	models := make([]mongo.WriteModel, len(docs))
+	for i, doc := range docs {
+		set := bson.M{consts.ColSeq: doc.Seq}
+		if i == 0 {
+			set[consts.ColParentID] = *doc.ParentID
+		}
+		models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc.ID}).SetUpdate(bson.M{"$set": set})
+	}
+	_, err := m.collection.BulkWrite(ctx, models)
+	return err
+}
+
+// headState is the position a save appends at.
+type headState struct {
+	// id is the last message of the active branch, or "" if the branch is empty.
+	id string
+	// isSet is false for sessions without a stored head, whose head is their last message.
+	isSet bool
+	// lastSeq is the sequence number of the last message written to the session.
+	lastSeq int64
+}
+
+// head returns the head of a session. Without a stored head, the last message
+// other than the excluded ones is used.
+func (m *MongoMemory) head(ctx context.Context, sessionID string, exclude ...primitive.ObjectID) (headState, error) {
+	var session SessionDoc
+	err := m.sessions.FindOne(ctx, sessionFilter(ctx, sessionID),
+		options.FindOne().SetProjection(bson.M{consts.ColHeadID: 1, consts.ColLastSeq: 1}),
+	).Decode(&session)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return headState{}, err
+	}
+	if session.HeadID != nil {
+		return headState{id: *session.HeadID, isSet: true, lastSeq: session.LastSeq}, nil
+	}
+
+	filter := sessionFilter(ctx, sessionID)
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
+	}
+	var last MessageDoc
+	err = m.collection.FindOne(ctx, filter,
+		options.FindOne().
+			SetSort(bson.D{{Key: consts.ColSeq, Value: -1}, {Key: consts.ColCreatedAt, Value: -1}, {Key: "_id", Value: -1}}).
+			SetProjection(bson.M{"_id": 1}),
+	).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return headState{lastSeq: session.LastSeq}, nil
+	}
+	if err != nil {
+		return headState{}, err
+	}
+	return headState{id: last.ID.Hex(), lastSeq: session.LastSeq}, nil
+}
+
+// History implements memory.Brancher.
+func (m *MongoMemory) History(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	nodes, err := m.loadNodes(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := m.head(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.BranchPath(nodes, state.id)
+}
+
+// Fork implements memory.Brancher.
+func (m *MongoMemory) Fork(ctx context.Context, sessionID, messageID string) error {
+	nodes, err := m.loadNodes(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	parentID, err := memory.ParentOf(nodes, messageID)
+	if err != nil {
+		return err
+	}
+	return m.setHead(ctx, sessionID, parentID)
+}
+
+// Checkout implements memory.Brancher.
+func (m *MongoMemory) Checkout(ctx context.Context, sessionID, messageID string) error {
+	if messageID != "" {
+		id, err := primitive.ObjectIDFromHex(messageID)
+		if err != nil {
+			return memory.ErrMessageNotFound
+		}
+		filter := sessionFilter(ctx, sessionID)
+		filter["_id"] = id
+		count, err := m.collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return memory.ErrMessageNotFound
+		}
+	}
+	return m.setHead(ctx, sessionID, messageID)
+}
+
+// Branches implements memory.Brancher.
+func (m *MongoMemory) Branches(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	nodes, err := m.loadNodes(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.Leaves(nodes), nil
+}
+
+func (m *MongoMemory) setHead(ctx context.Context, sessionID, messageID string) error {
+	_, err := m.sessions.UpdateOne(ctx, sessionFilter(ctx, sessionID),
+		bson.M{"$set": bson.M{consts.ColHeadID: messageID}},
+	)
+	return err
+}
+
+// loadNodes reads every message of a session in creation order.
+func (m *MongoMemory) loadNodes(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	// Messages written before sequence numbers have no seq and sort first, by time.
+	opts := options.Find().SetSort(bson.D{{Key: consts.ColSeq, Value: 1}, {Key: consts.ColCreatedAt, Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, sessionFilter(ctx, sessionID), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var nodes []memory.Node
+	for cursor.Next(ctx) {
+		var doc MessageDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		msg, err := toMessage(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		parentID := ""
+		if doc.ParentID != nil {
+			parentID = *doc.ParentID
+		} else if len(nodes) > 0 {
+			parentID = nodes[len(nodes)-1].ID
+		}
+		nodes = append(nodes, memory.Node{ID: doc.ID.Hex(), ParentID: parentID, Seq: doc.Seq, Message: msg})
+	}
+	return nodes, cursor.Err()
+}
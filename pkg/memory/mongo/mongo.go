@@ -3,22 +3,75 @@ package mongo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
 	"github.com/barekit/talos/pkg/memory/consts"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+func init() {
+	memory.Register(memory.TypeMongo, open, "mongodb", "mongodb+srv")
+}
+
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	opts := options.Client().ApplyURI(cfg.ConnectionString)
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongo: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("failed to ping mongo: %w", err)
+	}
+	dbName := consts.DefaultDBName
+	if cfg.DBName != "" {
+		dbName = cfg.DBName
+	}
+	m := New(client, dbName, consts.TableNameMessages, WithRetention(cfg.Retention, cfg.SweepInterval))
+	if err := m.EnsureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.BackfillSessions(ctx); err != nil {
+		return nil, fmt.Errorf("failed to backfill sessions: %w", err)
+	}
+	return m, nil
+}
+
 type MongoMemory struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	sessions   *mongo.Collection
+	retention  time.Duration
+	interval   time.Duration
+	stop       func()
+}
+
+// Option configures a MongoMemory.
+type Option func(*MongoMemory)
+
+// WithRetention deletes sessions that have not been written to for retention,
+// checking every interval. A zero interval uses memory.StartSweeper's default.
+func WithRetention(retention, interval time.Duration) Option {
+	return func(m *MongoMemory) {
+		m.retention = retention
+		m.interval = interval
+	}
 }
 
 type MessageDoc struct {
+	ID primitive.ObjectID `bson:"_id,omitempty"`
+	// ParentID is the previous message on the branch, empty for the first message.
+	// Messages written before branching support have no parent and follow the previous message.
+	ParentID *string `bson:"parent_id,omitempty"`
+	// Seq orders the messages of a session. It is missing on messages written before sequence numbers.
+	Seq        int64     `bson:"seq,omitempty"`
+	TenantID   string    `bson:"tenant_id,omitempty"`
 	SessionID  string    `bson:"session_id"`
 	Role       string    `bson:"role"`
 	Content    string    `bson:"content"`
@@ -28,73 +81,186 @@ type MessageDoc struct {
 }
 
 // New creates a new MongoMemory adapter.
-func New(client *mongo.Client, dbName, collectionName string) *MongoMemory {
-	return &MongoMemory{
+func New(client *mongo.Client, dbName, collectionName string, opts ...Option) *MongoMemory {
+	m := &MongoMemory{
 		client:     client,
 		collection: client.Database(dbName).Collection(collectionName),
+		sessions:   client.Database(dbName).Collection(consts.TableNameSessions),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.retention > 0 {
+		m.stop = memory.StartSweeper(m, m.retention, m.interval)
 	}
+	return m
 }
 
-func (m *MongoMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
-	var toolCallsJSON string
-	if len(msg.ToolCalls) > 0 {
-		b, err := json.Marshal(msg.ToolCalls)
-		if err != nil {
-			return fmt.Errorf("failed to marshal tool calls: %w", err)
-		}
-		toolCallsJSON = string(b)
+// Close stops the retention sweeper and disconnects the Mongo client. Only call it when
+// the memory owns the client, as it does when opened with memory.NewFactory.
+func (m *MongoMemory) Close(ctx context.Context) error {
+	if m.stop != nil {
+		m.stop()
+		m.stop = nil
 	}
+	return m.client.Disconnect(ctx)
+}
 
-	doc := MessageDoc{
-		SessionID:  sessionID,
-		Role:       string(msg.Role),
-		Content:    msg.Content,
-		ToolCalls:  toolCallsJSON,
-		ToolCallID: msg.ToolCallID,
-		CreatedAt:  time.Now(),
+// EnsureIndexes creates the indexes used by Load, ListSessions and Sweep. It drops the
+// expires_at TTL indexes of earlier versions, which expired messages independently of their session.
+func (m *MongoMemory) EnsureIndexes(ctx context.Context) error {
+	messageIndexes := []mongo.IndexModel{{
+		Keys: bson.D{{Key: consts.ColTenantID, Value: 1}, {Key: consts.ColSessionID, Value: 1}, {Key: consts.ColSeq, Value: 1}},
+	}}
+	sessionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: consts.ColTenantID, Value: 1}, {Key: consts.ColSessionID, Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: consts.ColTenantID, Value: 1}, {Key: consts.ColUserID, Value: 1}, {Key: consts.ColUpdatedAt, Value: -1}},
+		},
+	}
+	if m.retention > 0 {
+		sessionIndexes = append(sessionIndexes, mongo.IndexModel{Keys: bson.D{{Key: consts.ColUpdatedAt, Value: 1}}})
 	}
 
-	_, err := m.collection.InsertOne(ctx, doc)
+	if _, err := m.collection.Indexes().CreateMany(ctx, messageIndexes); err != nil {
+		return fmt.Errorf("failed to create message indexes: %w", err)
+	}
+	if _, err := m.sessions.Indexes().CreateMany(ctx, sessionIndexes); err != nil {
+		return fmt.Errorf("failed to create session indexes: %w", err)
+	}
+	for _, c := range []*mongo.Collection{m.collection, m.sessions} {
+		if err := dropIndex(ctx, c, consts.ColExpiresAt+"_1"); err != nil {
+			return fmt.Errorf("failed to drop expiry index: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropIndex drops the named index of a collection if it exists.
+func dropIndex(ctx context.Context, c *mongo.Collection, name string) error {
+	_, err := c.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
 	return err
 }
 
-func (m *MongoMemory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
-	filter := bson.M{consts.ColSessionID: sessionID}
-	opts := options.Find().SetSort(bson.M{consts.ColCreatedAt: 1})
+// tenantFilter matches the tenant of the context. Documents of the default tenant
+// are stored without a tenant_id field.
+func tenantFilter(ctx context.Context) interface{} {
+	if tenantID := memory.TenantFromContext(ctx); tenantID != "" {
+		return tenantID
+	}
+	return nil
+}
 
-	cursor, err := m.collection.Find(ctx, filter, opts)
+func (m *MongoMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+	return m.SaveBatch(ctx, sessionID, []llm.Message{msg})
+}
+
+// SaveBatch implements memory.BatchSaver. The messages are inserted with one InsertMany
+// and only become visible to Load once the session head is moved to the last of them.
+// Concurrent saves are ordered by the session's sequence number, see append.
+func (m *MongoMemory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]MessageDoc, len(msgs))
+	for i, msg := range msgs {
+		var toolCallsJSON string
+		if len(msg.ToolCalls) > 0 {
+			b, err := json.Marshal(msg.ToolCalls)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tool calls: %w", err)
+			}
+			toolCallsJSON = string(b)
+		}
+
+		docs[i] = MessageDoc{
+			TenantID:   memory.TenantFromContext(ctx),
+			SessionID:  sessionID,
+			Role:       string(msg.Role),
+			Content:    msg.Content,
+			ToolCalls:  toolCallsJSON,
+			ToolCallID: msg.ToolCallID,
+			CreatedAt:  now,
+		}
+	}
+
+	if err := m.touch(ctx, sessionID, now); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return m.append(ctx, sessionID, docs)
+}
+
+// Load loads the active branch of a session.
+func (m *MongoMemory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
+	path, err := m.History(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	return memory.Messages(path), nil
+}
+
+func toMessage(doc MessageDoc) (llm.Message, error) {
+	msg := llm.Message{
+		Role:       llm.Role(doc.Role),
+		Content:    doc.Content,
+		ToolCallID: doc.ToolCallID,
+	}
+
+	if doc.ToolCalls != "" {
+		var toolCalls []llm.ToolCall
+		if err := json.Unmarshal([]byte(doc.ToolCalls), &toolCalls); err != nil {
+			return msg, fmt.Errorf("failed to unmarshal tool calls: %w", err)
+		}
+		msg.ToolCalls = toolCalls
+	}
+
+	return msg, nil
+}
+
+// Sweep implements memory.Sweeper, deleting idle sessions with their messages. Messages
+// written after the cutoff belong to a newer session of the same ID and are kept.
+func (m *MongoMemory) Sweep(ctx context.Context, cutoff time.Time) (int64, error) {
+	cursor, err := m.sessions.Find(ctx, bson.M{consts.ColUpdatedAt: bson.M{"$lt": cutoff}})
+	if err != nil {
+		return 0, err
+	}
 	defer cursor.Close(ctx)
 
-	var messages []llm.Message
+	var deleted int64
 	for cursor.Next(ctx) {
-		var doc MessageDoc
+		var doc SessionDoc
 		if err := cursor.Decode(&doc); err != nil {
-			return nil, err
+			return deleted, err
 		}
 
-		msg := llm.Message{
-			Role:       llm.Role(doc.Role),
-			Content:    doc.Content,
-			ToolCallID: doc.ToolCallID,
+		// Deleting the session first keeps a concurrent Save from losing its session document.
+		tenantCtx := memory.WithTenant(ctx, doc.TenantID)
+		filter := sessionFilter(tenantCtx, doc.SessionID)
+		filter[consts.ColUpdatedAt] = bson.M{"$lt": cutoff}
+		result, err := m.sessions.DeleteOne(ctx, filter)
+		if err != nil {
+			return deleted, err
 		}
-
-		if doc.ToolCalls != "" {
-			var toolCalls []llm.ToolCall
-			if err := json.Unmarshal([]byte(doc.ToolCalls), &toolCalls); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tool calls: %w", err)
-			}
-			msg.ToolCalls = toolCalls
+		if result.DeletedCount == 0 {
+			continue
 		}
 
-		messages = append(messages, msg)
-	}
-
-	if err := cursor.Err(); err != nil {
-		return nil, err
+		filter = sessionFilter(tenantCtx, doc.SessionID)
+		filter[consts.ColCreatedAt] = bson.M{"$lt": cutoff}
+		result, err = m.collection.DeleteMany(ctx, filter)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += result.DeletedCount
 	}
-
-	return messages, nil
+	return deleted, cursor.Err()
 }
@@ -0,0 +1,180 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionDoc is the stored form of a session record.
+type SessionDoc struct {
+	TenantID   string            `bson:"tenant_id,omitempty"`
+	SessionID  string            `bson:"session_id"`
+	UserID     string            `bson:"user_id,omitempty"`
+	Title      string            `bson:"title,omitempty"`
+	Tags       []string          `bson:"tags,omitempty"`
+	Attributes map[string]string `bson:"attributes,omitempty"`
+	CreatedAt  time.Time         `bson:"created_at"`
+	UpdatedAt  time.Time         `bson:"updated_at"`
+	// HeadID is the last message of the active branch, empty before the first message.
+	// It is missing for sessions written before branching support, whose head is their last message.
+	HeadID *string `bson:"head_id,omitempty"`
+	// LastSeq is the sequence number of the last message written to the session.
+	LastSeq int64 `bson:"last_seq,omitempty"`
+}
+
+func sessionFilter(ctx context.Context, sessionID string) bson.M {
+	return bson.M{consts.ColTenantID: tenantFilter(ctx), consts.ColSessionID: sessionID}
+}
+
+// touch creates the session record if needed and bumps its activity time.
+func (m *MongoMemory) touch(ctx context.Context, sessionID string, now time.Time) error {
+	_, err := m.sessions.UpdateOne(ctx,
+		sessionFilter(ctx, sessionID),
+		bson.M{"$set": bson.M{consts.ColUpdatedAt: now}, "$setOnInsert": bson.M{consts.ColCreatedAt: now}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// BackfillSessions creates the session documents of sessions written before session
+// documents existed, so they are listed, swept and migrated like any other session.
+// Open runs it automatically.
+func (m *MongoMemory) BackfillSessions(ctx context.Context) error {
+	cursor, err := m.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":               bson.M{consts.ColTenantID: "$" + consts.ColTenantID, consts.ColSessionID: "$" + consts.ColSessionID},
+			consts.ColCreatedAt: bson.M{"$min": "$" + consts.ColCreatedAt},
+			consts.ColUpdatedAt: bson.M{"$max": "$" + consts.ColCreatedAt},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var group struct {
+			ID struct {
+				TenantID  string `bson:"tenant_id"`
+				SessionID string `bson:"session_id"`
+			} `bson:"_id"`
+			CreatedAt time.Time `bson:"created_at"`
+			UpdatedAt time.Time `bson:"updated_at"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+
+		tenantCtx := memory.WithTenant(ctx, group.ID.TenantID)
+		_, err := m.sessions.UpdateOne(tenantCtx,
+			sessionFilter(tenantCtx, group.ID.SessionID),
+			bson.M{"$setOnInsert": bson.M{consts.ColCreatedAt: group.CreatedAt, consts.ColUpdatedAt: group.UpdatedAt}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// SaveSession implements memory.SessionStore.
+func (m *MongoMemory) SaveSession(ctx context.Context, session memory.Session) error {
+	if err := memory.PrepareSession(ctx, &session); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	set := bson.M{
+		consts.ColUserID:     session.UserID,
+		consts.ColTitle:      session.Title,
+		consts.ColTags:       session.Tags,
+		consts.ColAttributes: session.Attributes,
+		consts.ColUpdatedAt:  now,
+	}
+
+	_, err := m.sessions.UpdateOne(ctx,
+		sessionFilter(ctx, session.ID),
+		bson.M{"$set": set, "$setOnInsert": bson.M{consts.ColCreatedAt: now}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetSession implements memory.SessionStore.
+func (m *MongoMemory) GetSession(ctx context.Context, sessionID string) (*memory.Session, error) {
+	var doc SessionDoc
+	err := m.sessions.FindOne(ctx, sessionFilter(ctx, sessionID)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, memory.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := toSession(doc)
+	return &s, nil
+}
+
+// ListSessions implements memory.SessionStore.
+func (m *MongoMemory) ListSessions(ctx context.Context, query memory.SessionQuery) ([]memory.Session, error) {
+	filter := bson.M{consts.ColTenantID: tenantFilter(ctx)}
+	if query.UserID != "" {
+		filter[consts.ColUserID] = query.UserID
+	}
+	if query.Tag != "" {
+		filter[consts.ColTags] = query.Tag
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: consts.ColUpdatedAt, Value: -1}})
+	if query.Offset > 0 {
+		opts.SetSkip(int64(query.Offset))
+	}
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := m.sessions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []memory.Session
+	for cursor.Next(ctx) {
+		var doc SessionDoc
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, toSession(doc))
+	}
+	return sessions, cursor.Err()
+}
+
+// DeleteSession implements memory.SessionStore.
+func (m *MongoMemory) DeleteSession(ctx context.Context, sessionID string) error {
+	if _, err := m.collection.DeleteMany(ctx, sessionFilter(ctx, sessionID)); err != nil {
+		return err
+	}
+	_, err := m.sessions.DeleteOne(ctx, sessionFilter(ctx, sessionID))
+	return err
+}
+
+func toSession(doc SessionDoc) memory.Session {
+	return memory.Session{
+		ID:         doc.SessionID,
+		TenantID:   doc.TenantID,
+		UserID:     doc.UserID,
+		Title:      doc.Title,
+		Tags:       doc.Tags,
+		Attributes: doc.Attributes,
+		CreatedAt:  doc.CreatedAt,
+		UpdatedAt:  doc.UpdatedAt,
+	}
+}
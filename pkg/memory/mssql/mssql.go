@@ -1,18 +1,38 @@
 package mssql
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/barekit/talos/pkg/memory"
 	gormmem "github.com/barekit/talos/pkg/memory/gorm"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
 )
 
+func init() {
+	memory.Register(memory.TypeMSSQL, open, "sqlserver", "mssql")
+}
+
+// open accepts the driver's own DSN formats; mssql:// URLs are read as sqlserver:// URLs.
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	dsn := cfg.ConnectionString
+	if strings.HasPrefix(strings.ToLower(dsn), "mssql://") {
+		dsn = "sqlserver://" + dsn[len("mssql://"):]
+	}
+	m, err := New(dsn, gormmem.WithRetention(cfg.Retention, cfg.SweepInterval))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // New creates a new MSSQL memory.
-func New(dsn string) (*gormmem.Memory, error) {
+func New(dsn string, opts ...gormmem.Option) (*gormmem.Memory, error) {
 	db, err := gorm.Open(sqlserver.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open mssql: %w", err)
 	}
-	return gormmem.New(db)
+	return gormmem.New(db, opts...)
 }
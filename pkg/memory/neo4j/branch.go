@@ -0,0 +1,170 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// History implements memory.Brancher. The branch is read by following FOLLOWS
+// relationships from the head back to the first message.
+func (m *Neo4jMemory) History(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	result, err := dbSession.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`
+		MATCH (s:%[1]s {%[2]s: $tenantID, id: $sessionID})-[:%[3]s]->(h:%[4]s {id: s.%[5]s})
+		MATCH path = (h)-[:%[6]s*0..]->(root:%[4]s)
+		WHERE NOT (root)-[:%[6]s]->()
+		UNWIND reverse(nodes(path)) AS m
+		RETURN m
+		`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage,
+			consts.ColHeadID, consts.RelFollows)
+
+		records, err := tx.Run(ctx, query, map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		path := []memory.Node{}
+		for records.Next(ctx) {
+			value, _ := records.Record().Get("m")
+			parentID := ""
+			if len(path) > 0 {
+				parentID = path[len(path)-1].ID
+			}
+			node, err := toNode(value, parentID)
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, node)
+		}
+		return path, records.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]memory.Node), nil
+}
+
+// Fork implements memory.Brancher.
+func (m *Neo4jMemory) Fork(ctx context.Context, sessionID, messageID string) error {
+	query := fmt.Sprintf(`
+	MATCH (s:%s {%s: $tenantID, id: $sessionID})-[:%s]->(m:%s {id: $messageID})
+	OPTIONAL MATCH (m)-[:%s]->(p:%s)
+	SET s.%s = coalesce(p.id, '')
+	RETURN count(m) AS matched
+	`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage,
+		consts.RelFollows, consts.LabelMessage, consts.ColHeadID)
+	return m.moveHead(ctx, query, sessionID, messageID)
+}
+
+// Checkout implements memory.Brancher.
+func (m *Neo4jMemory) Checkout(ctx context.Context, sessionID, messageID string) error {
+	query := fmt.Sprintf(`
+	MATCH (s:%s {%s: $tenantID, id: $sessionID})-[:%s]->(m:%s {id: $messageID})
+	SET s.%s = m.id
+	RETURN count(m) AS matched
+	`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage, consts.ColHeadID)
+	if messageID == "" {
+		// Checking out the root of an unknown session is a no-op, as in the other adapters.
+		query = fmt.Sprintf(`
+		OPTIONAL MATCH (s:%s {%s: $tenantID, id: $sessionID})
+		SET s.%s = ''
+		RETURN 1 AS matched
+		`, consts.LabelSession, consts.ColTenantID, consts.ColHeadID)
+	}
+	return m.moveHead(ctx, query, sessionID, messageID)
+}
+
+// moveHead runs a query updating the head, which returns 0 as matched if the message is not in the session.
+func (m *Neo4jMemory) moveHead(ctx context.Context, query, sessionID, messageID string) error {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	matched, err := dbSession.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, query, map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+			"messageID": messageID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !result.Next(ctx) {
+			return int64(0), result.Err()
+		}
+		matched, _ := result.Record().Get("matched")
+		return matched, nil
+	})
+	if err != nil {
+		return err
+	}
+	if n, _ := matched.(int64); n == 0 {
+		return fmt.Errorf("%w: %s", memory.ErrMessageNotFound, messageID)
+	}
+	return nil
+}
+
+// Branches implements memory.Brancher.
+func (m *Neo4jMemory) Branches(ctx context.Context, sessionID string) ([]memory.Node, error) {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	result, err := dbSession.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`
+		MATCH (s:%[1]s {%[2]s: $tenantID, id: $sessionID})-[:%[3]s]->(m:%[4]s)
+		WHERE NOT ()-[:%[5]s]->(m)
+		OPTIONAL MATCH (m)-[:%[5]s]->(p:%[4]s)
+		RETURN m, p.id AS parent_id
+		ORDER BY m.%[6]s, m.id
+		`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage,
+			consts.RelFollows, consts.ColSeq)
+
+		records, err := tx.Run(ctx, query, map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var leaves []memory.Node
+		for records.Next(ctx) {
+			record := records.Record()
+			value, _ := record.Get("m")
+			parentID, _ := record.Get("parent_id")
+			parent, _ := parentID.(string)
+			node, err := toNode(value, parent)
+			if err != nil {
+				return nil, err
+			}
+			leaves = append(leaves, node)
+		}
+		return leaves, records.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]memory.Node), nil
+}
+
+func toNode(value any, parentID string) (memory.Node, error) {
+	n, ok := value.(neo4j.Node)
+	if !ok {
+		return memory.Node{}, fmt.Errorf("unexpected message value %T", value)
+	}
+	msg, err := toMessage(n.Props)
+	if err != nil {
+		return memory.Node{}, err
+	}
+	seq, _ := n.Props[consts.ColSeq].(int64)
+	return memory.Node{ID: stringProp(n.Props, "id"), ParentID: parentID, Seq: seq, Message: msg}, nil
+}
@@ -0,0 +1,182 @@
+package neo4j
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"github.com/barekit/talos/pkg/memory/entity"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// propertyPrefix marks entity properties among the node's own fields.
+const propertyPrefix = "prop_"
+
+var _ entity.Store = (*Neo4jMemory)(nil)
+
+// UpsertGraph implements entity.Store. Entities are Entity nodes keyed by tenant and
+// normalized name, relations are RELATES_TO relationships carrying their type, and the
+// session links to the entities it mentions.
+func (m *Neo4jMemory) UpsertGraph(ctx context.Context, sessionID string, graph entity.Graph) error {
+	entities := make([]map[string]any, 0, len(graph.Entities))
+	for _, e := range graph.Entities {
+		props := make(map[string]any, len(e.Properties))
+		for k, v := range e.Properties {
+			props[propertyPrefix+k] = v
+		}
+		entities = append(entities, map[string]any{
+			"key": entity.Key(e.Name), "name": e.Name, "type": e.Type, "props": props,
+		})
+	}
+	relations := make([]map[string]any, 0, len(graph.Relations))
+	for _, r := range graph.Relations {
+		relations = append(relations, map[string]any{
+			"source": r.Source, "sourceKey": entity.Key(r.Source),
+			"target": r.Target, "targetKey": entity.Key(r.Target),
+			"type": entity.RelationType(r.Type),
+		})
+	}
+
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	_, err := dbSession.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		params := map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+			"entities":  entities,
+			"relations": relations,
+		}
+
+		query := fmt.Sprintf(`
+		UNWIND $entities AS ent
+		MERGE (e:%[1]s {%[2]s: $tenantID, key: ent.key})
+		ON CREATE SET e.name = ent.name, e.%[3]s = datetime()
+		SET e.%[4]s = datetime(), e += ent.props
+		FOREACH (_ IN CASE WHEN ent.type <> '' THEN [1] ELSE [] END | SET e.type = ent.type)
+		WITH e
+		OPTIONAL MATCH (s:%[5]s {%[2]s: $tenantID, id: $sessionID})
+		FOREACH (session IN CASE WHEN s IS NULL THEN [] ELSE [s] END | MERGE (session)-[:%[6]s]->(e))
+		`, consts.LabelEntity, consts.ColTenantID, consts.ColCreatedAt, consts.ColUpdatedAt,
+			consts.LabelSession, consts.RelMentions)
+		if _, err := tx.Run(ctx, query, params); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`
+		UNWIND $relations AS rel
+		MERGE (a:%[1]s {%[2]s: $tenantID, key: rel.sourceKey})
+		ON CREATE SET a.name = rel.source, a.%[3]s = datetime(), a.%[4]s = datetime()
+		MERGE (b:%[1]s {%[2]s: $tenantID, key: rel.targetKey})
+		ON CREATE SET b.name = rel.target, b.%[3]s = datetime(), b.%[4]s = datetime()
+		MERGE (a)-[r:%[5]s {type: rel.type}]->(b)
+		SET r.%[4]s = datetime()
+		`, consts.LabelEntity, consts.ColTenantID, consts.ColCreatedAt, consts.ColUpdatedAt, consts.RelRelatesTo)
+		_, err := tx.Run(ctx, query, params)
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert entity graph: %w", err)
+	}
+	return nil
+}
+
+// RecallGraph implements entity.Store. Only names of up to entity.MaxNameWords words are recalled.
+func (m *Neo4jMemory) RecallGraph(ctx context.Context, text string, limit int) (entity.Graph, error) {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	result, err := dbSession.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		tenantID := memory.TenantFromContext(ctx)
+		text := entity.Key(text)
+
+		// The spans of text that could be names are looked up through the entity_key index.
+		query := fmt.Sprintf(`MATCH (e:%s {%s: $tenantID}) WHERE e.key IN $candidates RETURN e.key AS key, e.name AS name`,
+			consts.LabelEntity, consts.ColTenantID)
+		records, err := tx.Run(ctx, query, map[string]any{"tenantID": tenantID, "candidates": entity.Candidates(text)})
+		if err != nil {
+			return nil, err
+		}
+		var keys []string
+		for records.Next(ctx) {
+			record := records.Record()
+			key, _ := record.Get("key")
+			name, _ := record.Get("name")
+			if k, ok := key.(string); ok && entity.Mentions(text, fmt.Sprint(name)) {
+				keys = append(keys, k)
+			}
+		}
+		if err := records.Err(); err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return entity.Graph{}, nil
+		}
+		keys = entity.Rank(text, keys)
+		if limit > 0 && len(keys) > limit {
+			keys = keys[:limit]
+		}
+
+		query = fmt.Sprintf(`
+		MATCH (e:%[1]s {%[2]s: $tenantID}) WHERE e.key IN $keys
+		OPTIONAL MATCH (e)-[r:%[3]s]-(:%[1]s)
+		WITH e, collect(DISTINCT r) AS rels
+		UNWIND CASE WHEN size(rels) = 0 THEN [null] ELSE rels END AS r
+		RETURN e, startNode(r) AS a, r.type AS type, endNode(r) AS b
+		`, consts.LabelEntity, consts.ColTenantID, consts.RelRelatesTo)
+		records, err = tx.Run(ctx, query, map[string]any{"tenantID": tenantID, "keys": keys})
+		if err != nil {
+			return nil, err
+		}
+
+		entities := make(map[string]entity.Entity)
+		relations := make(map[entity.Relation]bool)
+		for records.Next(ctx) {
+			record := records.Record()
+			var names [3]string
+			for i, field := range []string{"e", "a", "b"} {
+				value, _ := record.Get(field)
+				if node, ok := value.(neo4j.Node); ok {
+					e := toEntity(node.Props)
+					entities[entity.Key(e.Name)] = e
+					names[i] = e.Name
+				}
+			}
+			if typ, ok := record.AsMap()["type"].(string); ok && names[1] != "" && names[2] != "" {
+				relations[entity.Relation{Source: names[1], Target: names[2], Type: typ}] = true
+			}
+		}
+		if err := records.Err(); err != nil {
+			return nil, err
+		}
+
+		var graph entity.Graph
+		for _, e := range entities {
+			graph.Entities = append(graph.Entities, e)
+		}
+		for r := range relations {
+			graph.Relations = append(graph.Relations, r)
+		}
+		graph.Sort()
+		return graph, nil
+	})
+	if err != nil {
+		return entity.Graph{}, fmt.Errorf("failed to recall entity graph: %w", err)
+	}
+	return result.(entity.Graph), nil
+}
+
+func toEntity(props map[string]any) entity.Entity {
+	e := entity.Entity{Name: stringProp(props, "name"), Type: stringProp(props, "type")}
+	for k, v := range props {
+		if name, ok := strings.CutPrefix(k, propertyPrefix); ok {
+			if e.Properties == nil {
+				e.Properties = make(map[string]string)
+			}
+			e.Properties[name] = fmt.Sprint(v)
+		}
+	}
+	return e
+}
@@ -4,19 +4,65 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"time"
 
 	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
 	"github.com/barekit/talos/pkg/memory/consts"
+	"github.com/google/uuid"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 )
 
+func init() {
+	memory.Register(memory.TypeNeo4j, open, "neo4j", "neo4j+s", "neo4j+ssc", "bolt", "bolt+s", "bolt+ssc")
+}
+
+// open reads credentials from cfg, or from the user info of the URI when cfg has none.
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	uri, username, password := cfg.ConnectionString, cfg.Username, cfg.Password
+	if u, err := url.Parse(uri); err == nil && u.User != nil {
+		if username == "" {
+			username = u.User.Username()
+			password, _ = u.User.Password()
+		}
+		u.User = nil
+		uri = u.String()
+	}
+
+	dbName := "neo4j" // Default Neo4j DB is typically "neo4j", not "talos"
+	if cfg.DBName != "" {
+		dbName = cfg.DBName
+	}
+	m, err := New(uri, username, password, dbName, WithRetention(cfg.Retention, cfg.SweepInterval))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 type Neo4jMemory struct {
-	driver neo4j.DriverWithContext
-	dbName string
+	driver    neo4j.DriverWithContext
+	dbName    string
+	retention time.Duration
+	interval  time.Duration
+	stop      func()
+}
+
+// Option configures a Neo4jMemory.
+type Option func(*Neo4jMemory)
+
+// WithRetention deletes sessions that have not been written to for retention,
+// checking every interval. A zero interval uses memory.StartSweeper's default.
+func WithRetention(retention, interval time.Duration) Option {
+	return func(m *Neo4jMemory) {
+		m.retention = retention
+		m.interval = interval
+	}
 }
 
 // New creates a new Neo4jMemory adapter.
-func New(uri, username, password, dbName string) (*Neo4jMemory, error) {
+func New(uri, username, password, dbName string, opts ...Option) (*Neo4jMemory, error) {
 	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(username, password, ""))
 	if err != nil {
 		return nil, err
@@ -26,39 +72,196 @@ func New(uri, username, password, dbName string) (*Neo4jMemory, error) {
 		return nil, err
 	}
 
-	return &Neo4jMemory{
+	m := &Neo4jMemory{
 		driver: driver,
 		dbName: dbName,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if err := m.migrate(context.Background()); err != nil {
+		return nil, err
+	}
+	if m.retention > 0 {
+		m.stop = memory.StartSweeper(m, m.retention, m.interval)
+	}
+	return m, nil
 }
 
-func (m *Neo4jMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+// schemaVersion is stored on the schema node once the backfills of migrate have run.
+const schemaVersion = 1
+
+// migrate assigns sessions created before tenant scoping to the default tenant, merges
+// duplicate session nodes so sessions can be made unique, links the messages of sessions
+// created before branching support into a single branch, and numbers the messages of
+// sessions created before sequence numbers. Sessions without activity timestamps get them
+// from their messages. These backfills scan every session, so they only run while the
+// schema node records an older version.
+func (m *Neo4jMemory) migrate(ctx context.Context) error {
 	session := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
 	defer session.Close(ctx)
 
-	var toolCallsJSON string
-	if len(msg.ToolCalls) > 0 {
-		b, err := json.Marshal(msg.ToolCalls)
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`OPTIONAL MATCH (v:%s) RETURN coalesce(max(v.version), 0)`, consts.LabelSchema)
+		result, err := tx.Run(ctx, query, nil)
+		if err != nil {
+			return nil, err
+		}
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if version, _ := record.Values[0].(int64); version >= schemaVersion {
+			return nil, nil
+		}
+
+		query = fmt.Sprintf(`MATCH (s:%s) WHERE s.%s IS NULL SET s.%s = ''`,
+			consts.LabelSession, consts.ColTenantID, consts.ColTenantID)
+		if _, err := tx.Run(ctx, query, nil); err != nil {
+			return nil, err
+		}
+
+		// Concurrent first saves could create a session twice before the uniqueness
+		// constraint existed. The duplicates' messages and mentions move to one node.
+		query = fmt.Sprintf(`
+		MATCH (s:%[1]s)
+		WITH s.%[2]s AS tenantID, s.id AS id, collect(s) AS sessions
+		WHERE size(sessions) > 1
+		WITH head(sessions) AS keep, tail(sessions) AS duplicates
+		UNWIND duplicates AS dup
+		CALL {
+			WITH keep, dup
+			MATCH (dup)-[:%[3]s]->(m:%[4]s)
+			MERGE (keep)-[:%[3]s]->(m)
+		}
+		CALL {
+			WITH keep, dup
+			MATCH (dup)-[:%[5]s]->(e:%[6]s)
+			MERGE (keep)-[:%[5]s]->(e)
+		}
+		SET keep.%[7]s = CASE WHEN coalesce(dup.%[7]s, 0) > coalesce(keep.%[7]s, 0) THEN dup.%[7]s ELSE keep.%[7]s END
+		DETACH DELETE dup
+		`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage,
+			consts.RelMentions, consts.LabelEntity, consts.ColLastSeq)
+		if _, err := tx.Run(ctx, query, nil); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`
+		MATCH (s:%[1]s) WHERE s.%[2]s IS NULL
+		OPTIONAL MATCH (s)-[:%[3]s]->(m:%[4]s)
+		WITH s, m ORDER BY m.%[5]s
+		WITH s, collect(m) AS messages
+		FOREACH (m IN messages | SET m.id = coalesce(m.id, randomUUID()))
+		FOREACH (i IN range(1, size(messages) - 1) |
+			FOREACH (m IN [messages[i]] | FOREACH (p IN [messages[i - 1]] | MERGE (m)-[:%[6]s]->(p))))
+		SET s.%[2]s = CASE WHEN size(messages) = 0 THEN '' ELSE messages[size(messages) - 1].id END
+		`, consts.LabelSession, consts.ColHeadID, consts.RelHasMessage, consts.LabelMessage,
+			consts.ColCreatedAt, consts.RelFollows)
+		if _, err := tx.Run(ctx, query, nil); err != nil {
+			return nil, err
+		}
+
+		// Sessions created before activity timestamps take them from their messages,
+		// so Sweep and ListSessions see them.
+		query = fmt.Sprintf(`
+		MATCH (s:%[1]s) WHERE s.%[2]s IS NULL
+		OPTIONAL MATCH (s)-[:%[3]s]->(m:%[4]s)
+		WITH s, min(m.%[5]s) AS first, max(m.%[5]s) AS last
+		SET s.%[5]s = coalesce(s.%[5]s, first, datetime()), s.%[2]s = coalesce(last, datetime())
+		`, consts.LabelSession, consts.ColUpdatedAt, consts.RelHasMessage, consts.LabelMessage, consts.ColCreatedAt)
+		if _, err := tx.Run(ctx, query, nil); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`
+		MATCH (s:%[1]s) WHERE s.%[2]s IS NULL
+		OPTIONAL MATCH (s)-[:%[3]s]->(m:%[4]s)
+		WITH s, m ORDER BY m.%[5]s, m.id
+		WITH s, collect(m) AS messages
+		FOREACH (i IN range(0, size(messages) - 1) | FOREACH (m IN [messages[i]] | SET m.%[6]s = i + 1))
+		SET s.%[2]s = size(messages)
+		`, consts.LabelSession, consts.ColLastSeq, consts.RelHasMessage, consts.LabelMessage,
+			consts.ColCreatedAt, consts.ColSeq)
+		if _, err := tx.Run(ctx, query, nil); err != nil {
+			return nil, err
+		}
+
+		query = fmt.Sprintf(`MERGE (v:%s) SET v.version = $version`, consts.LabelSchema)
+		_, err = tx.Run(ctx, query, map[string]any{"version": schemaVersion})
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate sessions: %w", err)
+	}
+
+	// Schema changes cannot share a transaction with data changes.
+	// The session constraint also makes concurrent MERGEs of a new session wait for each other.
+	schema := []string{
+		fmt.Sprintf(`CREATE CONSTRAINT session_key IF NOT EXISTS FOR (s:%s) REQUIRE (s.%s, s.id) IS UNIQUE`,
+			consts.LabelSession, consts.ColTenantID),
+		fmt.Sprintf(`CREATE INDEX entity_key IF NOT EXISTS FOR (e:%s) ON (e.%s, e.key)`,
+			consts.LabelEntity, consts.ColTenantID),
+	}
+	for _, query := range schema {
+		_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, query, nil)
+			return nil, err
+		})
 		if err != nil {
-			return fmt.Errorf("failed to marshal tool calls: %w", err)
+			return fmt.Errorf("failed to create schema: %w", err)
 		}
-		toolCallsJSON = string(b)
+	}
+	return nil
+}
+
+func (m *Neo4jMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+	return m.SaveBatch(ctx, sessionID, []llm.Message{msg})
+}
+
+// SaveBatch implements memory.BatchSaver. All messages are written in one transaction.
+func (m *Neo4jMemory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	if len(msgs) == 0 {
+		return nil
 	}
 
+	toolCalls := make([]string, len(msgs))
+	for i, msg := range msgs {
+		if len(msg.ToolCalls) > 0 {
+			b, err := json.Marshal(msg.ToolCalls)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tool calls: %w", err)
+			}
+			toolCalls[i] = string(b)
+		}
+	}
+
+	session := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer session.Close(ctx)
+
 	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		// Create Session node if not exists
 		querySession := fmt.Sprintf(`
-		MERGE (s:%s {id: $sessionID})
+		MERGE (s:%s {%s: $tenantID, id: $sessionID})
+		ON CREATE SET s.%s = datetime()
+		SET s.%s = datetime()
 		RETURN s
-		`, consts.LabelSession)
-		if _, err := tx.Run(ctx, querySession, map[string]any{"sessionID": sessionID}); err != nil {
+		`, consts.LabelSession, consts.ColTenantID, consts.ColCreatedAt, consts.ColUpdatedAt)
+		params := map[string]any{"tenantID": memory.TenantFromContext(ctx), "sessionID": sessionID}
+		if _, err := tx.Run(ctx, querySession, params); err != nil {
 			return nil, err
 		}
 
-		// Create Message node and link to Session
+		// Create Message node, link it to the Session and append it to the active branch.
+		// The session_key constraint makes the MERGE above lock the session node, even
+		// for a new session, so concurrent saves see each other's head and sequence number.
+		// Deadlocks between them are retried by ExecuteWrite.
 		queryMsg := fmt.Sprintf(`
-		MATCH (s:%s {id: $sessionID})
+		MATCH (s:%s {%s: $tenantID, id: $sessionID})
+		SET s.%s = coalesce(s.%s, 0) + 1
 		CREATE (m:%s {
+			id: $messageID,
+			%s: s.%s,
 			%s: $role,
 			%s: $content,
 			%s: $toolCalls,
@@ -66,79 +269,96 @@ func (m *Neo4jMemory) Save(ctx context.Context, sessionID string, msg llm.Messag
 			%s: datetime()
 		})
 		CREATE (s)-[:%s]->(m)
-		RETURN m
-		`, consts.LabelSession, consts.LabelMessage,
+		WITH s, m
+		OPTIONAL MATCH (s)-[:%s]->(p:%s {id: s.%s})
+		FOREACH (parent IN CASE WHEN p IS NULL THEN [] ELSE [p] END | CREATE (m)-[:%s]->(parent))
+		SET s.%s = m.id
+		`, consts.LabelSession, consts.ColTenantID, consts.ColLastSeq, consts.ColLastSeq,
+			consts.LabelMessage, consts.ColSeq, consts.ColLastSeq,
 			consts.ColRole, consts.ColContent, consts.ColToolCalls, consts.ColToolCallID, consts.ColCreatedAt,
-			consts.RelHasMessage)
+			consts.RelHasMessage, consts.RelHasMessage, consts.LabelMessage, consts.ColHeadID,
+			consts.RelFollows, consts.ColHeadID)
 
-		params := map[string]any{
-			"sessionID":  sessionID,
-			"role":       string(msg.Role),
-			"content":    msg.Content,
-			"toolCalls":  toolCallsJSON,
-			"toolCallID": msg.ToolCallID,
+		for i, msg := range msgs {
+			params = map[string]any{
+				"tenantID":   memory.TenantFromContext(ctx),
+				"sessionID":  sessionID,
+				"messageID":  uuid.NewString(),
+				"role":       string(msg.Role),
+				"content":    msg.Content,
+				"toolCalls":  toolCalls[i],
+				"toolCallID": msg.ToolCallID,
+			}
+			if _, err := tx.Run(ctx, queryMsg, params); err != nil {
+				return nil, err
+			}
 		}
-		_, err := tx.Run(ctx, queryMsg, params)
-		return nil, err
+		return nil, nil
 	})
 
 	return err
 }
 
+// Load loads the active branch of a session.
 func (m *Neo4jMemory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
+	path, err := m.History(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return memory.Messages(path), nil
+}
+
+func toMessage(props map[string]any) (llm.Message, error) {
+	msg := llm.Message{
+		Role:       llm.Role(stringProp(props, consts.ColRole)),
+		Content:    stringProp(props, consts.ColContent),
+		ToolCallID: stringProp(props, consts.ColToolCallID),
+	}
+
+	if toolCalls := stringProp(props, consts.ColToolCalls); toolCalls != "" {
+		if err := json.Unmarshal([]byte(toolCalls), &msg.ToolCalls); err != nil {
+			return msg, fmt.Errorf("failed to unmarshal tool calls: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// Sweep implements memory.Sweeper, deleting idle sessions with their messages.
+func (m *Neo4jMemory) Sweep(ctx context.Context, cutoff time.Time) (int64, error) {
 	session := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
 	defer session.Close(ctx)
 
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+	result, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
 		query := fmt.Sprintf(`
-		MATCH (s:%s {id: $sessionID})-[:%s]->(m:%s)
-		RETURN m.%s, m.%s, m.%s, m.%s
-		ORDER BY m.%s ASC
-		`, consts.LabelSession, consts.RelHasMessage, consts.LabelMessage,
-			consts.ColRole, consts.ColContent, consts.ColToolCalls, consts.ColToolCallID,
-			consts.ColCreatedAt)
-
-		result, err := tx.Run(ctx, query, map[string]any{"sessionID": sessionID})
+		MATCH (s:%s) WHERE s.%s < $cutoff
+		OPTIONAL MATCH (s)-[:%s]->(m:%s)
+		DETACH DELETE m
+		WITH DISTINCT s
+		DETACH DELETE s
+		RETURN count(s) AS deleted
+		`, consts.LabelSession, consts.ColUpdatedAt, consts.RelHasMessage, consts.LabelMessage)
+
+		result, err := tx.Run(ctx, query, map[string]any{"cutoff": cutoff})
 		if err != nil {
 			return nil, err
 		}
-
-		var messages []llm.Message
-		for result.Next(ctx) {
-			record := result.Record()
-
-			role, _ := record.Get("m." + consts.ColRole)
-			content, _ := record.Get("m." + consts.ColContent)
-			toolCallsStr, _ := record.Get("m." + consts.ColToolCalls)
-			toolCallID, _ := record.Get("m." + consts.ColToolCallID)
-
-			msg := llm.Message{
-				Role:       llm.Role(role.(string)),
-				Content:    content.(string),
-				ToolCallID: toolCallID.(string),
-			}
-
-			if toolCallsStr != nil && toolCallsStr.(string) != "" {
-				var toolCalls []llm.ToolCall
-				if err := json.Unmarshal([]byte(toolCallsStr.(string)), &toolCalls); err != nil {
-					return nil, err
-				}
-				msg.ToolCalls = toolCalls
-			}
-
-			messages = append(messages, msg)
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, err
 		}
-
-		return messages, nil
+		deleted, _ := record.Get("deleted")
+		return deleted, nil
 	})
-
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-
-	return result.([]llm.Message), nil
+	return result.(int64), nil
 }
 
 func (m *Neo4jMemory) Close(ctx context.Context) error {
+	if m.stop != nil {
+		m.stop()
+		m.stop = nil
+	}
 	return m.driver.Close(ctx)
 }
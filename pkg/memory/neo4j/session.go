@@ -0,0 +1,194 @@
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/consts"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// SaveSession implements memory.SessionStore. Attributes are stored as a JSON string property.
+func (m *Neo4jMemory) SaveSession(ctx context.Context, session memory.Session) error {
+	if err := memory.PrepareSession(ctx, &session); err != nil {
+		return err
+	}
+
+	attributes := ""
+	if len(session.Attributes) > 0 {
+		b, err := json.Marshal(session.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal attributes: %w", err)
+		}
+		attributes = string(b)
+	}
+	tags := session.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	_, err := dbSession.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`
+		MERGE (s:%s {%s: $tenantID, id: $sessionID})
+		ON CREATE SET s.%s = datetime()
+		SET s.%s = $userID, s.%s = $title, s.%s = $tags, s.%s = $attributes, s.%s = datetime()
+		`, consts.LabelSession, consts.ColTenantID, consts.ColCreatedAt,
+			consts.ColUserID, consts.ColTitle, consts.ColTags, consts.ColAttributes, consts.ColUpdatedAt)
+
+		_, err := tx.Run(ctx, query, map[string]any{
+			"tenantID":   session.TenantID,
+			"sessionID":  session.ID,
+			"userID":     session.UserID,
+			"title":      session.Title,
+			"tags":       tags,
+			"attributes": attributes,
+		})
+		return nil, err
+	})
+	return err
+}
+
+// GetSession implements memory.SessionStore.
+func (m *Neo4jMemory) GetSession(ctx context.Context, sessionID string) (*memory.Session, error) {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	result, err := dbSession.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`MATCH (s:%s {%s: $tenantID, id: $sessionID}) RETURN s`,
+			consts.LabelSession, consts.ColTenantID)
+		return collectSessions(ctx, tx, query, map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := result.([]memory.Session)
+	if len(sessions) == 0 {
+		return nil, memory.ErrSessionNotFound
+	}
+	return &sessions[0], nil
+}
+
+// ListSessions implements memory.SessionStore.
+func (m *Neo4jMemory) ListSessions(ctx context.Context, query memory.SessionQuery) ([]memory.Session, error) {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	page := ""
+	if query.Offset > 0 {
+		page += " SKIP $offset"
+	}
+	if query.Limit > 0 {
+		page += " LIMIT $limit"
+	}
+
+	result, err := dbSession.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		cypher := fmt.Sprintf(`
+		MATCH (s:%[1]s {%[2]s: $tenantID})
+		WHERE ($userID = '' OR s.%[3]s = $userID) AND ($tag = '' OR $tag IN coalesce(s.%[4]s, []))
+		RETURN s
+		ORDER BY s.%[5]s DESC%[6]s
+		`, consts.LabelSession, consts.ColTenantID, consts.ColUserID, consts.ColTags, consts.ColUpdatedAt, page)
+
+		return collectSessions(ctx, tx, cypher, map[string]any{
+			"tenantID": memory.TenantFromContext(ctx),
+			"userID":   query.UserID,
+			"tag":      query.Tag,
+			"offset":   query.Offset,
+			"limit":    query.Limit,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]memory.Session), nil
+}
+
+// DeleteSession implements memory.SessionStore.
+func (m *Neo4jMemory) DeleteSession(ctx context.Context, sessionID string) error {
+	dbSession := m.driver.NewSession(ctx, neo4j.SessionConfig{DatabaseName: m.dbName})
+	defer dbSession.Close(ctx)
+
+	_, err := dbSession.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		query := fmt.Sprintf(`
+		MATCH (s:%s {%s: $tenantID, id: $sessionID})
+		OPTIONAL MATCH (s)-[:%s]->(m:%s)
+		DETACH DELETE m
+		WITH DISTINCT s
+		DETACH DELETE s
+		`, consts.LabelSession, consts.ColTenantID, consts.RelHasMessage, consts.LabelMessage)
+
+		_, err := tx.Run(ctx, query, map[string]any{
+			"tenantID":  memory.TenantFromContext(ctx),
+			"sessionID": sessionID,
+		})
+		return nil, err
+	})
+	return err
+}
+
+// collectSessions runs a query returning session nodes as "s".
+func collectSessions(ctx context.Context, tx neo4j.ManagedTransaction, query string, params map[string]any) ([]memory.Session, error) {
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []memory.Session{}
+	for result.Next(ctx) {
+		value, _ := result.Record().Get("s")
+		node, ok := value.(neo4j.Node)
+		if !ok {
+			return nil, fmt.Errorf("unexpected session value %T", value)
+		}
+		s, err := toSession(node.Props)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, result.Err()
+}
+
+func toSession(props map[string]any) (memory.Session, error) {
+	s := memory.Session{
+		ID:        stringProp(props, "id"),
+		TenantID:  stringProp(props, consts.ColTenantID),
+		UserID:    stringProp(props, consts.ColUserID),
+		Title:     stringProp(props, consts.ColTitle),
+		CreatedAt: timeProp(props, consts.ColCreatedAt),
+		UpdatedAt: timeProp(props, consts.ColUpdatedAt),
+	}
+	if tags, ok := props[consts.ColTags].([]any); ok {
+		for _, tag := range tags {
+			if t, ok := tag.(string); ok {
+				s.Tags = append(s.Tags, t)
+			}
+		}
+	}
+	if attributes := stringProp(props, consts.ColAttributes); attributes != "" {
+		if err := json.Unmarshal([]byte(attributes), &s.Attributes); err != nil {
+			return s, fmt.Errorf("failed to unmarshal attributes of session %s: %w", s.ID, err)
+		}
+	}
+	return s, nil
+}
+
+func stringProp(props map[string]any, key string) string {
+	s, _ := props[key].(string)
+	return s
+}
+
+func timeProp(props map[string]any, key string) time.Time {
+	t, _ := props[key].(time.Time)
+	return t
+}
@@ -1,18 +1,32 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/barekit/talos/pkg/memory"
 	gormmem "github.com/barekit/talos/pkg/memory/gorm"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+func init() {
+	memory.Register(memory.TypePostgres, open, "postgres", "postgresql")
+}
+
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	m, err := New(cfg.ConnectionString, gormmem.WithRetention(cfg.Retention, cfg.SweepInterval))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // New creates a new Postgres memory.
-func New(dsn string) (*gormmem.Memory, error) {
+func New(dsn string, opts ...gormmem.Option) (*gormmem.Memory, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open postgres: %w", err)
 	}
-	return gormmem.New(db)
+	return gormmem.New(db, opts...)
 }
@@ -3,37 +3,128 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
 	"github.com/redis/go-redis/v9"
 )
 
+func init() {
+	memory.Register(memory.TypeRedis, open, "redis", "rediss")
+}
+
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	opts, err := redis.ParseURL(cfg.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+	m := New(client, WithTTL(cfg.Retention))
+	if err := m.BackfillSessions(ctx); err != nil {
+		return nil, fmt.Errorf("failed to backfill sessions: %w", err)
+	}
+	return m, nil
+}
+
 // RedisMemory implements Memory using Redis.
 type RedisMemory struct {
 	client *redis.Client
+	ttl    time.Duration
+}
+
+// Option configures a RedisMemory.
+type Option func(*RedisMemory)
+
+// WithTTL expires a session when it has not been written to for ttl.
+// The expiry is refreshed on every Save.
+func WithTTL(ttl time.Duration) Option {
+	return func(m *RedisMemory) {
+		m.ttl = ttl
+	}
 }
 
 // New creates a new RedisMemory.
-func New(client *redis.Client) *RedisMemory {
-	return &RedisMemory{client: client}
+func New(client *redis.Client, opts ...Option) *RedisMemory {
+	m := &RedisMemory{client: client}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Close closes the Redis client. Only call it when the memory owns the client,
+// as it does when opened with memory.NewFactory.
+func (m *RedisMemory) Close() error {
+	return m.client.Close()
+}
+
+// entry is a stored message. Seq is the message's position in the session, starting at 1;
+// it is 0 for messages stored before sequence numbers were introduced.
+type entry struct {
+	Seq int64 `json:"seq,omitempty"`
+	llm.Message
 }
 
 // Save saves a message to Redis.
-// Messages are stored as a JSON list under "session:{sessionID}".
+// Messages are stored as a JSON list under "session:{sessionID}", prefixed
+// with "tenant:{tenantID}:" for sessions of a tenant.
 func (m *RedisMemory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
-	b, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+	return m.SaveBatch(ctx, sessionID, []llm.Message{msg})
+}
+
+// SaveBatch implements memory.BatchSaver with a single MULTI/EXEC.
+// The list is watched while sequence numbers are assigned from its length,
+// so a concurrent save makes the transaction fail and retry.
+func (m *RedisMemory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	if len(msgs) == 0 {
+		return nil
 	}
 
-	key := fmt.Sprintf("session:%s", sessionID)
-	return m.client.RPush(ctx, key, b).Err()
+	k := keysFor(ctx)
+	key := k.messages(sessionID)
+	return memory.RetrySeq(ctx, func() error {
+		err := m.client.Watch(ctx, func(tx *redis.Tx) error {
+			lastSeq, err := tx.LLen(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+
+			values := make([]interface{}, len(msgs))
+			for i, msg := range msgs {
+				b, err := json.Marshal(entry{Seq: lastSeq + int64(i) + 1, Message: msg})
+				if err != nil {
+					return fmt.Errorf("failed to marshal message: %w", err)
+				}
+				values[i] = b
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.RPush(ctx, key, values...)
+				if m.ttl > 0 {
+					pipe.Expire(ctx, key, m.ttl)
+				}
+				m.touch(ctx, pipe, k, sessionID, time.Now())
+				return nil
+			})
+			return err
+		}, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			return memory.ErrSeqConflict
+		}
+		return err
+	})
 }
 
-// Load loads messages from Redis.
+// Load loads messages from Redis in sequence order.
 func (m *RedisMemory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
-	key := fmt.Sprintf("session:%s", sessionID)
+	key := keysFor(ctx).messages(sessionID)
 
 	// Get all items in the list
 	result, err := m.client.LRange(ctx, key, 0, -1).Result()
@@ -41,14 +132,17 @@ func (m *RedisMemory) Load(ctx context.Context, sessionID string) ([]llm.Message
 		return nil, err
 	}
 
-	messages := make([]llm.Message, len(result))
+	entries := make([]entry, len(result))
 	for i, item := range result {
-		var msg llm.Message
-		if err := json.Unmarshal([]byte(item), &msg); err != nil {
+		if err := json.Unmarshal([]byte(item), &entries[i]); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal message at index %d: %w", i, err)
 		}
-		messages[i] = msg
 	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
 
+	messages := make([]llm.Message, len(entries))
+	for i, e := range entries {
+		messages[i] = e.Message
+	}
 	return messages, nil
 }
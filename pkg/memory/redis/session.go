@@ -0,0 +1,284 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of the session metadata hash.
+const (
+	metaData      = "data"
+	metaCreatedAt = "created_at"
+	metaUpdatedAt = "updated_at"
+)
+
+// listBatch is the number of session records fetched per round-trip by ListSessions.
+const listBatch = 100
+
+// keys returns the Redis keys of a session in the tenant of ctx.
+// The default tenant keeps the unprefixed "session:{id}" layout.
+type keys struct {
+	prefix string
+}
+
+func keysFor(ctx context.Context) keys {
+	if tenantID := memory.TenantFromContext(ctx); tenantID != "" {
+		return keys{prefix: fmt.Sprintf("tenant:%s:", tenantID)}
+	}
+	return keys{}
+}
+
+// messages is the list holding the session's messages.
+func (k keys) messages(sessionID string) string {
+	return k.prefix + "session:" + sessionID
+}
+
+// meta is the hash holding the session record.
+func (k keys) meta(sessionID string) string {
+	return k.prefix + "session_meta:" + sessionID
+}
+
+// index is the sorted set of the tenant's sessions scored by last activity.
+func (k keys) index() string {
+	return k.prefix + "sessions"
+}
+
+// user is the set of a user's sessions.
+func (k keys) user(userID string) string {
+	return k.prefix + "user_sessions:" + userID
+}
+
+// messagesKey splits a message list key into the keys of its tenant and the session ID.
+func messagesKey(key string) (keys, string, bool) {
+	if rest, ok := strings.CutPrefix(key, "tenant:"); ok {
+		tenantID, sessionID, ok := strings.Cut(rest, ":session:")
+		return keys{prefix: "tenant:" + tenantID + ":"}, sessionID, ok && tenantID != ""
+	}
+	sessionID, ok := strings.CutPrefix(key, "session:")
+	return keys{}, sessionID, ok
+}
+
+// BackfillSessions creates the session records and index entries of sessions written
+// before session records existed, so they are listed and migrated like any other session.
+// Their activity time is the time of the backfill. Open runs it automatically.
+func (m *RedisMemory) BackfillSessions(ctx context.Context) error {
+	iter := m.client.ScanType(ctx, 0, "*session:*", listBatch, "list").Iterator()
+	for iter.Next(ctx) {
+		k, sessionID, ok := messagesKey(iter.Val())
+		if !ok {
+			continue
+		}
+		exists, err := m.client.Exists(ctx, k.meta(sessionID)).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			m.touch(ctx, pipe, k, sessionID, time.Now())
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// touch queues the updates that create the session record if needed and bump its activity time.
+func (m *RedisMemory) touch(ctx context.Context, pipe redis.Pipeliner, k keys, sessionID string, now time.Time) {
+	ts := now.UnixNano()
+	pipe.HSetNX(ctx, k.meta(sessionID), metaCreatedAt, ts)
+	pipe.HSet(ctx, k.meta(sessionID), metaUpdatedAt, ts)
+	pipe.ZAdd(ctx, k.index(), redis.Z{Score: float64(now.UnixMilli()), Member: sessionID})
+	if m.ttl > 0 {
+		pipe.Expire(ctx, k.meta(sessionID), m.ttl)
+	}
+}
+
+// SaveSession implements memory.SessionStore.
+func (m *RedisMemory) SaveSession(ctx context.Context, session memory.Session) error {
+	if err := memory.PrepareSession(ctx, &session); err != nil {
+		return err
+	}
+	k := keysFor(ctx)
+
+	previous, err := m.GetSession(ctx, session.ID)
+	if err != nil && !errors.Is(err, memory.ErrSessionNotFound) {
+		return err
+	}
+
+	data, err := json.Marshal(memory.Session{
+		UserID:     session.UserID,
+		Title:      session.Title,
+		Tags:       session.Tags,
+		Attributes: session.Attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	_, err = m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, k.meta(session.ID), metaData, data)
+		m.touch(ctx, pipe, k, session.ID, time.Now())
+		if previous != nil && previous.UserID != "" && previous.UserID != session.UserID {
+			pipe.SRem(ctx, k.user(previous.UserID), session.ID)
+		}
+		if session.UserID != "" {
+			pipe.SAdd(ctx, k.user(session.UserID), session.ID)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetSession implements memory.SessionStore.
+func (m *RedisMemory) GetSession(ctx context.Context, sessionID string) (*memory.Session, error) {
+	fields, err := m.client.HGetAll(ctx, keysFor(ctx).meta(sessionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, memory.ErrSessionNotFound
+	}
+	return toSession(ctx, sessionID, fields)
+}
+
+// ListSessions implements memory.SessionStore.
+// Without a UserID, sessions are read in activity order and the scan stops once the page is full.
+func (m *RedisMemory) ListSessions(ctx context.Context, query memory.SessionQuery) ([]memory.Session, error) {
+	k := keysFor(ctx)
+
+	if query.UserID != "" {
+		ids, err := m.client.SMembers(ctx, k.user(query.UserID)).Result()
+		if err != nil {
+			return nil, err
+		}
+		sessions, err := m.fetch(ctx, k, ids, query)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(sessions, func(i, j int) bool {
+			return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+		})
+		return query.Page(sessions), nil
+	}
+
+	want := query.Offset + query.Limit
+	var sessions []memory.Session
+	for start := int64(0); ; start += listBatch {
+		ids, err := m.client.ZRevRange(ctx, k.index(), start, start+listBatch-1).Result()
+		if err != nil {
+			return nil, err
+		}
+		batch, err := m.fetch(ctx, k, ids, query)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, batch...)
+
+		if len(ids) < listBatch || (query.Limit > 0 && len(sessions) >= want) {
+			break
+		}
+	}
+	return query.Page(sessions), nil
+}
+
+// fetch loads the records of the given sessions that match the query, in the given order.
+// Index entries of sessions that have expired are removed.
+func (m *RedisMemory) fetch(ctx context.Context, k keys, ids []string, query memory.SessionQuery) ([]memory.Session, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err := m.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, k.meta(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []memory.Session
+	var expired []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			expired = append(expired, ids[i])
+			continue
+		}
+		s, err := toSession(ctx, ids[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		if query.Matches(*s) {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	if len(expired) > 0 {
+		if err := m.client.ZRem(ctx, k.index(), expired...).Err(); err != nil {
+			return nil, err
+		}
+		if query.UserID != "" {
+			if err := m.client.SRem(ctx, k.user(query.UserID), expired...).Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sessions, nil
+}
+
+// DeleteSession implements memory.SessionStore.
+func (m *RedisMemory) DeleteSession(ctx context.Context, sessionID string) error {
+	k := keysFor(ctx)
+
+	previous, err := m.GetSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, memory.ErrSessionNotFound) {
+		return err
+	}
+
+	_, err = m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, k.messages(sessionID), k.meta(sessionID))
+		pipe.ZRem(ctx, k.index(), sessionID)
+		if previous != nil && previous.UserID != "" {
+			pipe.SRem(ctx, k.user(previous.UserID), sessionID)
+		}
+		return nil
+	})
+	return err
+}
+
+func toSession(ctx context.Context, sessionID string, fields map[string]string) (*memory.Session, error) {
+	s := &memory.Session{}
+	if data := fields[metaData]; data != "" {
+		if err := json.Unmarshal([]byte(data), s); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal session %s: %w", sessionID, err)
+		}
+	}
+	s.ID = sessionID
+	s.TenantID = memory.TenantFromContext(ctx)
+	s.CreatedAt = parseTime(fields[metaCreatedAt])
+	s.UpdatedAt = parseTime(fields[metaUpdatedAt])
+	return s, nil
+}
+
+func parseTime(s string) time.Time {
+	ns, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Sweeper is implemented by adapters without native expiry, which delete expired sessions themselves.
+type Sweeper interface {
+	// Sweep deletes the sessions that have not been written to since cutoff
+	// and returns the number of records removed.
+	Sweep(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// StartSweeper runs s.Sweep for sessions idle longer than retention, every interval,
+// until the returned stop function is called. A zero interval uses the retention
+// period capped at one hour.
+func StartSweeper(s Sweeper, retention, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = min(retention, time.Hour)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			n, err := s.Sweep(ctx, time.Now().Add(-retention))
+			if err != nil && ctx.Err() == nil {
+				slog.Error("Failed to sweep expired sessions", "error", err)
+			} else if n > 0 {
+				slog.Info("Swept expired sessions", "records", n)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSeqConflict is returned by an adapter's write when another writer claimed the
+// same sequence numbers first. The write has no effect and can be retried.
+var ErrSeqConflict = errors.New("sequence number conflict")
+
+// maxSeqRetries bounds how often RetrySeq reruns a write.
+const maxSeqRetries = 100
+
+// RetrySeq runs write until it does not fail with ErrSeqConflict.
+//
+// Adapters number the messages of a session 1, 2, 3, ... in write order and sort by that
+// number, because timestamps of messages saved in quick succession can tie. A write reads
+// the session's last sequence number, stores its messages after it and advances it with a
+// compare-and-set, returning ErrSeqConflict if the number moved in the meantime.
+func RetrySeq(ctx context.Context, write func() error) error {
+	for attempt := 0; attempt < maxSeqRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := write(); !errors.Is(err, ErrSeqConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to save after %d attempts: %w", maxSeqRetries, ErrSeqConflict)
+}
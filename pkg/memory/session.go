@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a session does not exist in the caller's tenant.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session describes a conversation. Adapters create the record on the first Save
+// and bump UpdatedAt on every Save.
+type Session struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	// Tags must not contain commas.
+	Tags       []string          `json:"tags,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// HasTag reports whether the session carries tag.
+func (s Session) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionQuery selects sessions for ListSessions. Empty fields match everything.
+type SessionQuery struct {
+	UserID string
+	Tag    string
+	// Limit caps the number of sessions returned. Zero returns all.
+	Limit  int
+	Offset int
+}
+
+// Matches reports whether a session satisfies the query filters.
+func (q SessionQuery) Matches(s Session) bool {
+	if q.UserID != "" && s.UserID != q.UserID {
+		return false
+	}
+	return q.Tag == "" || s.HasTag(q.Tag)
+}
+
+// Page applies a query's offset and limit to an ordered slice of sessions.
+func (q SessionQuery) Page(sessions []Session) []Session {
+	if q.Offset > 0 {
+		if q.Offset >= len(sessions) {
+			return nil
+		}
+		sessions = sessions[q.Offset:]
+	}
+	if q.Limit > 0 && len(sessions) > q.Limit {
+		sessions = sessions[:q.Limit]
+	}
+	return sessions
+}
+
+// SessionStore is implemented by adapters that keep session records.
+// Every method is scoped to the tenant in the context.
+type SessionStore interface {
+	// SaveSession creates or updates the user, title, tags and attributes of a session.
+	SaveSession(ctx context.Context, session Session) error
+	// GetSession returns ErrSessionNotFound if the session does not exist.
+	GetSession(ctx context.Context, sessionID string) (*Session, error)
+	// ListSessions returns matching sessions, most recently active first.
+	ListSessions(ctx context.Context, query SessionQuery) ([]Session, error)
+	// DeleteSession removes a session and its messages.
+	DeleteSession(ctx context.Context, sessionID string) error
+}
+
+type tenantKey struct{}
+
+// WithTenant scopes every memory operation made with the returned context to tenantID.
+// Sessions of different tenants are invisible to each other, even with equal session IDs.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, or "" for the default tenant.
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
+// PrepareSession validates a session passed to SaveSession and sets its tenant from the context.
+func PrepareSession(ctx context.Context, session *Session) error {
+	tenantID := TenantFromContext(ctx)
+	if session.TenantID != "" && session.TenantID != tenantID {
+		return fmt.Errorf("session tenant %q does not match context tenant %q", session.TenantID, tenantID)
+	}
+	if session.ID == "" {
+		return fmt.Errorf("session id is required")
+	}
+	for _, tag := range session.Tags {
+		if tag == "" || strings.Contains(tag, ",") {
+			return fmt.Errorf("invalid session tag: %q", tag)
+		}
+	}
+	session.TenantID = tenantID
+	return nil
+}
@@ -1,18 +1,41 @@
 package sqlite
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
+	"github.com/barekit/talos/pkg/memory"
 	gormmem "github.com/barekit/talos/pkg/memory/gorm"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+func init() {
+	memory.Register(memory.TypeSQLite, open, "sqlite", "sqlite3")
+}
+
+// open accepts a file path or a sqlite:// URL such as sqlite:///var/lib/talos.db.
+func open(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+	dsn := cfg.ConnectionString
+	for _, prefix := range []string{"sqlite://", "sqlite3://"} {
+		if strings.HasPrefix(strings.ToLower(dsn), prefix) {
+			dsn = dsn[len(prefix):]
+			break
+		}
+	}
+	m, err := New(dsn, gormmem.WithRetention(cfg.Retention, cfg.SweepInterval))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // New creates a new SQLite memory.
-func New(dsn string) (*gormmem.Memory, error) {
+func New(dsn string, opts ...gormmem.Option) (*gormmem.Memory, error) {
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open sqlite: %w", err)
 	}
-	return gormmem.New(db)
+	return gormmem.New(db, opts...)
 }
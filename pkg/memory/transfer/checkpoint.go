@@ -0,0 +1,97 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// Checkpoint records which sessions a run has finished.
+type Checkpoint interface {
+	// Done reports whether the session identified by key was finished.
+	Done(ctx context.Context, key string) (bool, error)
+	// Mark records the session identified by key as finished.
+	Mark(ctx context.Context, key string) error
+}
+
+// checkpointKey identifies a session across tenants.
+func checkpointKey(s memory.Session) string {
+	return s.TenantID + "\t" + s.ID
+}
+
+// FileCheckpoint keeps finished sessions in a file, one per line. Lines are appended
+// as sessions finish, so the file survives an interrupted run.
+type FileCheckpoint struct {
+	Path string
+
+	mu   sync.Mutex
+	done map[string]bool
+}
+
+// Done implements Checkpoint. The file is read on first use; a missing file means nothing is done.
+func (c *FileCheckpoint) Done(ctx context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return false, err
+	}
+	return c.done[key], nil
+}
+
+// Mark implements Checkpoint.
+func (c *FileCheckpoint) Mark(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.done[key] = true
+	return nil
+}
+
+func (c *FileCheckpoint) load() error {
+	if c.done != nil {
+		return nil
+	}
+
+	done := make(map[string]bool)
+	f, err := os.Open(c.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.done = done
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSuffix(scanner.Text(), "\r"); line != "" {
+			done[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	c.done = done
+	return nil
+}
@@ -0,0 +1,271 @@
+// Package transfer exports, imports and migrates sessions between memory backends.
+//
+// The export format is JSON Lines: one Record per session, holding the session
+// metadata and the messages of its active branch.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// FormatVersion is written to every exported record.
+const FormatVersion = 1
+
+// Record is one line of an export.
+type Record struct {
+	Version  int            `json:"version"`
+	Session  memory.Session `json:"session"`
+	Messages []llm.Message  `json:"messages"`
+}
+
+// Options configures Export, Import and Migrate.
+type Options struct {
+	// Tenants lists the tenants to read from the source. Empty uses the tenant of the context.
+	Tenants []string
+	// Query restricts the sessions read from the source. Its Limit and Offset are ignored.
+	Query memory.SessionQuery
+	// BatchSize is the number of sessions listed per ListSessions call. Defaults to 100.
+	BatchSize int
+	// Checkpoint records finished sessions so an interrupted run can be resumed.
+	Checkpoint Checkpoint
+	// Verify reloads every written session from the destination and compares message counts.
+	Verify bool
+	// Progress is called after every session.
+	Progress func(Result)
+}
+
+// Result counts the work of a run.
+type Result struct {
+	Sessions int
+	Messages int
+	// Skipped counts sessions already finished according to the checkpoint or the destination.
+	Skipped  int
+	Verified int
+}
+
+// ErrVerification is returned when a destination session does not hold the expected messages.
+var ErrVerification = errors.New("verification failed")
+
+// Export writes the sessions of src as JSON Lines. Sessions are enumerated through
+// memory.SessionStore, so src must implement it. Only the active branch of each session is exported.
+func Export(ctx context.Context, src memory.Memory, w io.Writer, opts Options) (*Result, error) {
+	enc := json.NewEncoder(w)
+	result := &Result{}
+
+	err := walk(ctx, src, opts, func(ctx context.Context, session memory.Session) error {
+		key := checkpointKey(session)
+		if done, err := opts.isDone(ctx, key); err != nil || done {
+			if done {
+				result.Skipped++
+			}
+			return err
+		}
+
+		messages, err := src.Load(ctx, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load session %s: %w", session.ID, err)
+		}
+		if err := enc.Encode(Record{Version: FormatVersion, Session: session, Messages: messages}); err != nil {
+			return fmt.Errorf("failed to write session %s: %w", session.ID, err)
+		}
+
+		result.Sessions++
+		result.Messages += len(messages)
+		return opts.finish(ctx, key, *result)
+	})
+	return result, err
+}
+
+// Import writes the records read from r into dst.
+func Import(ctx context.Context, dst memory.Memory, r io.Reader, opts Options) (*Result, error) {
+	dec := json.NewDecoder(r)
+	result := &Result{}
+
+	for {
+		var record Record
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read record %d: %w", result.Sessions+result.Skipped+1, err)
+		}
+		if record.Version > FormatVersion {
+			return result, fmt.Errorf("unsupported export version %d", record.Version)
+		}
+
+		ctx := memory.WithTenant(ctx, record.Session.TenantID)
+		done, err := opts.isDone(ctx, checkpointKey(record.Session))
+		if err != nil {
+			return result, err
+		}
+		if done {
+			result.Skipped++
+			continue
+		}
+		if err := write(ctx, dst, record.Session, record.Messages, opts, result); err != nil {
+			return result, err
+		}
+	}
+}
+
+// Migrate streams sessions from src into dst. src must implement memory.SessionStore.
+func Migrate(ctx context.Context, src, dst memory.Memory, opts Options) (*Result, error) {
+	result := &Result{}
+
+	err := walk(ctx, src, opts, func(ctx context.Context, session memory.Session) error {
+		if done, err := opts.isDone(ctx, checkpointKey(session)); err != nil || done {
+			if done {
+				result.Skipped++
+			}
+			return err
+		}
+
+		messages, err := src.Load(ctx, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load session %s: %w", session.ID, err)
+		}
+		return write(ctx, dst, session, messages, opts, result)
+	})
+	return result, err
+}
+
+// walk calls fn for every session of the source tenants, with the session's tenant in the context.
+func walk(ctx context.Context, src memory.Memory, opts Options, fn func(context.Context, memory.Session) error) error {
+	store, ok := src.(memory.SessionStore)
+	if !ok {
+		return fmt.Errorf("source %T does not implement memory.SessionStore", src)
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	tenants := opts.Tenants
+	if len(tenants) == 0 {
+		tenants = []string{memory.TenantFromContext(ctx)}
+	}
+
+	for _, tenantID := range tenants {
+		tenantCtx := memory.WithTenant(ctx, tenantID)
+
+		// Snapshot only the IDs before writing: pages are ordered by activity, so saving
+		// into a destination that shares the source store would otherwise reorder them.
+		var ids []string
+		query := opts.Query
+		query.Limit = batchSize
+		for query.Offset = 0; ; query.Offset += batchSize {
+			page, err := store.ListSessions(tenantCtx, query)
+			if err != nil {
+				return fmt.Errorf("failed to list sessions of tenant %q: %w", tenantID, err)
+			}
+			for _, session := range page {
+				ids = append(ids, session.ID)
+			}
+			if len(page) < batchSize {
+				break
+			}
+		}
+
+		for _, id := range ids {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			session, err := store.GetSession(tenantCtx, id)
+			if errors.Is(err, memory.ErrSessionNotFound) {
+				// Deleted since it was listed.
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get session %s: %w", id, err)
+			}
+			if err := fn(tenantCtx, *session); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write saves a session into dst, whose tenant is taken from ctx. Messages already present in dst, for example from an
+// interrupted run without a checkpoint, are not written again; the last of them must match the source.
+func write(ctx context.Context, dst memory.Memory, session memory.Session, messages []llm.Message, opts Options, result *Result) error {
+	existing, err := dst.Load(ctx, session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load destination session %s: %w", session.ID, err)
+	}
+	if len(existing) > len(messages) {
+		return fmt.Errorf("%w: destination session %s has %d messages, source has %d",
+			ErrVerification, session.ID, len(existing), len(messages))
+	}
+	if n := len(existing); n > 0 && !sameMessage(existing[n-1], messages[n-1]) {
+		return fmt.Errorf("%w: destination session %s diverges from the source at message %d",
+			ErrVerification, session.ID, n)
+	}
+
+	// One batch per session, so an interrupted run does not leave a partial session
+	// behind on destinations that implement memory.BatchSaver.
+	if err := memory.SaveBatch(ctx, dst, session.ID, messages[len(existing):]); err != nil {
+		return fmt.Errorf("failed to save messages of session %s: %w", session.ID, err)
+	}
+	if store, ok := dst.(memory.SessionStore); ok {
+		if err := store.SaveSession(ctx, session); err != nil {
+			return fmt.Errorf("failed to save session %s: %w", session.ID, err)
+		}
+	}
+
+	if opts.Verify {
+		loaded, err := dst.Load(ctx, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to verify session %s: %w", session.ID, err)
+		}
+		if len(loaded) != len(messages) {
+			return fmt.Errorf("%w: session %s has %d messages, expected %d",
+				ErrVerification, session.ID, len(loaded), len(messages))
+		}
+		result.Verified++
+	}
+
+	if len(existing) == len(messages) && len(messages) > 0 {
+		result.Skipped++
+	} else {
+		result.Sessions++
+		result.Messages += len(messages) - len(existing)
+	}
+	return opts.finish(ctx, checkpointKey(session), *result)
+}
+
+// sameMessage compares the fields every backend stores verbatim.
+func sameMessage(a, b llm.Message) bool {
+	return a.Role == b.Role && a.Content == b.Content && a.Name == b.Name && a.ToolCallID == b.ToolCallID
+}
+
+func (o Options) isDone(ctx context.Context, key string) (bool, error) {
+	if o.Checkpoint == nil {
+		return false, nil
+	}
+	done, err := o.Checkpoint.Done(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	return done, nil
+}
+
+func (o Options) finish(ctx context.Context, key string, result Result) error {
+	if o.Checkpoint != nil {
+		if err := o.Checkpoint.Mark(ctx, key); err != nil {
+			return fmt.Errorf("failed to write checkpoint: %w", err)
+		}
+	}
+	if o.Progress != nil {
+		o.Progress(result)
+	}
+	return nil
+}
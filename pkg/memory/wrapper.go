@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Wrapper forwards the optional interfaces of a wrapped Memory. Decorators embed it and
+// override the methods they transform. When the wrapped Memory lacks an interface,
+// the forwarded methods return an error wrapping errors.ErrUnsupported.
+type Wrapper struct {
+	Inner Memory
+}
+
+var (
+	_ SessionStore = Wrapper{}
+	_ Brancher     = Wrapper{}
+)
+
+// Unwrap returns the wrapped Memory.
+func (w Wrapper) Unwrap() Memory {
+	return w.Inner
+}
+
+func (w Wrapper) unsupported(iface string) error {
+	return fmt.Errorf("%T does not implement memory.%s: %w", w.Inner, iface, errors.ErrUnsupported)
+}
+
+// SaveSession implements SessionStore.
+func (w Wrapper) SaveSession(ctx context.Context, session Session) error {
+	store, ok := w.Inner.(SessionStore)
+	if !ok {
+		return w.unsupported("SessionStore")
+	}
+	return store.SaveSession(ctx, session)
+}
+
+// GetSession implements SessionStore.
+func (w Wrapper) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	store, ok := w.Inner.(SessionStore)
+	if !ok {
+		return nil, w.unsupported("SessionStore")
+	}
+	return store.GetSession(ctx, sessionID)
+}
+
+// ListSessions implements SessionStore.
+func (w Wrapper) ListSessions(ctx context.Context, query SessionQuery) ([]Session, error) {
+	store, ok := w.Inner.(SessionStore)
+	if !ok {
+		return nil, w.unsupported("SessionStore")
+	}
+	return store.ListSessions(ctx, query)
+}
+
+// DeleteSession implements SessionStore.
+func (w Wrapper) DeleteSession(ctx context.Context, sessionID string) error {
+	store, ok := w.Inner.(SessionStore)
+	if !ok {
+		return w.unsupported("SessionStore")
+	}
+	return store.DeleteSession(ctx, sessionID)
+}
+
+// History implements Brancher.
+func (w Wrapper) History(ctx context.Context, sessionID string) ([]Node, error) {
+	brancher, ok := w.Inner.(Brancher)
+	if !ok {
+		return nil, w.unsupported("Brancher")
+	}
+	return brancher.History(ctx, sessionID)
+}
+
+// Fork implements Brancher.
+func (w Wrapper) Fork(ctx context.Context, sessionID, messageID string) error {
+	brancher, ok := w.Inner.(Brancher)
+	if !ok {
+		return w.unsupported("Brancher")
+	}
+	return brancher.Fork(ctx, sessionID, messageID)
+}
+
+// Checkout implements Brancher.
+func (w Wrapper) Checkout(ctx context.Context, sessionID, messageID string) error {
+	brancher, ok := w.Inner.(Brancher)
+	if !ok {
+		return w.unsupported("Brancher")
+	}
+	return brancher.Checkout(ctx, sessionID, messageID)
+}
+
+// Branches implements Brancher.
+func (w Wrapper) Branches(ctx context.Context, sessionID string) ([]Node, error) {
+	brancher, ok := w.Inner.(Brancher)
+	if !ok {
+		return nil, w.unsupported("Brancher")
+	}
+	return brancher.Branches(ctx, sessionID)
+}
@@ -0,0 +1,140 @@
+package redact
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// Detector finds one kind of sensitive value.
+type Detector struct {
+	// Name labels replacements, e.g. "EMAIL" yields "[EMAIL]". It must be upper case letters, digits or underscores.
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate filters pattern matches, e.g. with a checksum. Nil accepts every match.
+	Validate func(match string) bool
+}
+
+var validName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// Pattern creates a Detector for a custom regular expression.
+func Pattern(name, expr string) (Detector, error) {
+	if !validName.MatchString(name) {
+		return Detector{}, fmt.Errorf("invalid detector name %q", name)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Detector{}, fmt.Errorf("failed to compile pattern %s: %w", name, err)
+	}
+	return Detector{Name: name, Pattern: re}, nil
+}
+
+// Email detects email addresses.
+func Email() Detector {
+	return Detector{
+		Name:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+}
+
+// IBAN detects international bank account numbers with a valid mod-97 checksum.
+func IBAN() Detector {
+	return Detector{
+		Name:     "IBAN",
+		Pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		Validate: validIBAN,
+	}
+}
+
+// Card detects payment card numbers with a valid Luhn checksum.
+func Card() Detector {
+	return Detector{
+		Name:     "CARD",
+		Pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Validate: validLuhn,
+	}
+}
+
+// Phone detects phone numbers: an international number starting with "+", or a local
+// number of nine to fifteen digits written in at least three groups, such as "555-123-4567"
+// or "(020) 7946 0958". Dots are not accepted as separators and date-shaped numbers are
+// skipped, so IP addresses, timestamps and plain IDs are left alone.
+func Phone() Detector {
+	return Detector{
+		Name:    "PHONE",
+		Pattern: regexp.MustCompile(`\+\d[\d ()-]{5,}\d\b|(?:\(\d{2,4}\)|\b\d{2,4})(?:[ -]\d{2,4}){2,}\b`),
+		Validate: func(match string) bool {
+			if datePattern.MatchString(match) {
+				return false
+			}
+			n := len(digits(match))
+			if strings.HasPrefix(match, "+") {
+				return n >= 7 && n <= 15
+			}
+			return n >= 9 && n <= 15
+		},
+	}
+}
+
+// datePattern matches the start of an ISO date such as "2024-01-15".
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// DefaultDetectors returns the built-in detectors. Earlier detectors win when matches overlap.
+func DefaultDetectors() []Detector {
+	return []Detector{Email(), IBAN(), Card(), Phone()}
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func validLuhn(match string) bool {
+	number := digits(match)
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and map letters to 10..35.
+	var b strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&b, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
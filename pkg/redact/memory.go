@@ -0,0 +1,52 @@
+package redact
+
+import (
+	"context"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+)
+
+// Memory redacts message content and tool call arguments before saving them to the wrapped memory.
+type Memory struct {
+	memory.Wrapper
+	redactor *Redactor
+}
+
+// NewMemory wraps a Memory.
+func NewMemory(inner memory.Memory, redactor *Redactor) *Memory {
+	return &Memory{Wrapper: memory.Wrapper{Inner: inner}, redactor: redactor}
+}
+
+// Save redacts and saves a message.
+func (m *Memory) Save(ctx context.Context, sessionID string, msg llm.Message) error {
+	return m.Inner.Save(ctx, sessionID, m.redactor.RedactMessage(msg))
+}
+
+// SaveBatch redacts and saves messages with the wrapped memory's batch save.
+func (m *Memory) SaveBatch(ctx context.Context, sessionID string, msgs []llm.Message) error {
+	redacted := make([]llm.Message, len(msgs))
+	for i, msg := range msgs {
+		redacted[i] = m.redactor.RedactMessage(msg)
+	}
+	return memory.SaveBatch(ctx, m.Inner, sessionID, redacted)
+}
+
+// Load loads messages as stored, with tokens in place of redacted values.
+func (m *Memory) Load(ctx context.Context, sessionID string) ([]llm.Message, error) {
+	return m.Inner.Load(ctx, sessionID)
+}
+
+// RedactMessage returns a copy of msg with its content and tool call arguments redacted.
+func (r *Redactor) RedactMessage(msg llm.Message) llm.Message {
+	msg.Content = r.Redact(msg.Content)
+	if len(msg.ToolCalls) > 0 {
+		toolCalls := make([]llm.ToolCall, len(msg.ToolCalls))
+		copy(toolCalls, msg.ToolCalls)
+		for i := range toolCalls {
+			toolCalls[i].Function.Arguments = r.Redact(toolCalls[i].Function.Arguments)
+		}
+		msg.ToolCalls = toolCalls
+	}
+	return msg
+}
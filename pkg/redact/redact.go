@@ -0,0 +1,173 @@
+// Package redact detects and removes personal data from prompts, stored messages and logs.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redactor replaces sensitive values found by its detectors. By default values are masked
+// as "[NAME]"; with a Vault they are replaced by reversible tokens such as "[EMAIL_1f3a9c0e2b7d4a61]".
+type Redactor struct {
+	detectors []Detector
+	vault     Vault
+	secret    []byte
+}
+
+// Option configures a Redactor.
+type Option func(*Redactor)
+
+// WithDetectors replaces the default detectors.
+func WithDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) {
+		r.detectors = detectors
+	}
+}
+
+// WithPatterns adds detectors after the current ones.
+func WithPatterns(detectors ...Detector) Option {
+	return func(r *Redactor) {
+		r.detectors = append(r.detectors, detectors...)
+	}
+}
+
+// WithTokenization replaces values with tokens that Restore maps back through the vault.
+// The same value always gets the same token from a Redactor, so the model can still
+// tell values apart.
+func WithTokenization(vault Vault) Option {
+	return func(r *Redactor) {
+		r.vault = vault
+	}
+}
+
+// WithSecret sets the key tokens are derived from. Without it every Redactor draws a random
+// key, so the same value gets a new token after a restart. Set it when the Vault outlives
+// the process.
+func WithSecret(secret []byte) Option {
+	return func(r *Redactor) {
+		r.secret = secret
+	}
+}
+
+// New creates a Redactor using DefaultDetectors.
+func New(opts ...Option) *Redactor {
+	r := &Redactor{detectors: DefaultDetectors()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.vault != nil && len(r.secret) == 0 {
+		r.secret = make([]byte, 32)
+		_, _ = rand.Read(r.secret)
+	}
+	return r
+}
+
+type span struct {
+	start, end int
+	name       string
+}
+
+// Redact returns s with every detected value replaced.
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, sp := range spans {
+			if start < sp.end && sp.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range r.detectors {
+		for _, loc := range d.Pattern.FindAllStringIndex(s, -1) {
+			match := s[loc[0]:loc[1]]
+			if (d.Validate != nil && !d.Validate(match)) || overlaps(loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{start: loc[0], end: loc[1], name: d.Name})
+		}
+	}
+	if len(spans) == 0 {
+		return s
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp.start])
+		b.WriteString(r.replacement(sp.name, s[sp.start:sp.end]))
+		last = sp.end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (r *Redactor) replacement(name, value string) string {
+	if r.vault == nil {
+		return "[" + name + "]"
+	}
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write([]byte(name + "\x00" + value))
+	token := "[" + name + "_" + hex.EncodeToString(mac.Sum(nil)[:8]) + "]"
+	r.vault.Store(token, value)
+	return token
+}
+
+var tokenPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_[0-9a-f]{16}\]`)
+
+// Restore replaces tokens with their original values. Unknown tokens, and all text
+// when tokenization is off, are left unchanged.
+func (r *Redactor) Restore(s string) string {
+	if r.vault == nil {
+		return s
+	}
+	return tokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if value, ok := r.vault.Lookup(token); ok {
+			return value
+		}
+		return token
+	})
+}
+
+// Vault keeps the original values of tokens.
+type Vault interface {
+	Store(token, value string)
+	Lookup(token string) (string, bool)
+}
+
+// MemoryVault is an in-process Vault. Tokens are lost when the process exits.
+type MemoryVault struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewMemoryVault creates an empty MemoryVault.
+func NewMemoryVault() *MemoryVault {
+	return &MemoryVault{values: make(map[string]string)}
+}
+
+// Store implements Vault.
+func (v *MemoryVault) Store(token, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[token] = value
+}
+
+// Lookup implements Vault.
+func (v *MemoryVault) Lookup(token string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[token]
+	return value, ok
+}
@@ -0,0 +1,81 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Handler is a slog.Handler that redacts the message and the attribute values of every
+// record before passing it on. Strings, errors and fmt.Stringer values are redacted as text;
+// other values are replaced by their redacted fmt.Sprint form when it holds sensitive data.
+// Numbers, booleans, times and durations are passed through.
+type Handler struct {
+	inner    slog.Handler
+	redactor *Redactor
+}
+
+// NewHandler wraps a slog.Handler:
+//
+//	slog.SetDefault(slog.New(redact.NewHandler(slog.Default().Handler(), redactor)))
+func NewHandler(inner slog.Handler, redactor *Redactor) *Handler {
+	return &Handler{inner: inner, redactor: redactor}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.attr(attr))
+		return true
+	})
+	return h.inner.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.attr(attr)
+	}
+	return &Handler{inner: h.inner.WithAttrs(redacted), redactor: h.redactor}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *Handler) attr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(h.redactor.Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redacted[i] = h.attr(a)
+		}
+		attr.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			attr.Value = slog.StringValue(h.redactor.Redact(v.Error()))
+		case fmt.Stringer:
+			attr.Value = slog.StringValue(h.redactor.Redact(v.String()))
+		default:
+			// Other values keep their type unless their printed form holds something to redact.
+			s := fmt.Sprint(v)
+			if redacted := h.redactor.Redact(s); redacted != s {
+				attr.Value = slog.StringValue(redacted)
+			}
+		}
+	}
+	return attr
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/barekit/talos/pkg/knowledge"
+)
+
+// KnowledgeSearchArgs are the arguments the model passes to a knowledge search tool.
+type KnowledgeSearchArgs struct {
+	Query  string                 `json:"query" description:"The search query. Phrase it as a standalone question or statement."`
+	Limit  int                    `json:"limit,omitempty" description:"Maximum number of results to return."`
+	Filter map[string]interface{} `json:"filter,omitempty" description:"Optional metadata filter. Each key must match the document metadata exactly; a list matches any of its values."`
+}
+
+// KnowledgeSearchResult is a single search hit returned to the model.
+type KnowledgeSearchResult struct {
+	ID       string                 `json:"id"`
+	Score    float32                `json:"score"`
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// KnowledgeSearchOption configures a knowledge search tool.
+type KnowledgeSearchOption func(*knowledgeSearch)
+
+// WithDefaultLimit sets the number of results returned when the model does not specify a limit.
+func WithDefaultLimit(limit int) KnowledgeSearchOption {
+	return func(s *knowledgeSearch) {
+		s.defaultLimit = limit
+	}
+}
+
+// WithMaxLimit caps the number of results the model can request.
+func WithMaxLimit(limit int) KnowledgeSearchOption {
+	return func(s *knowledgeSearch) {
+		s.maxLimit = limit
+	}
+}
+
+type knowledgeSearch struct {
+	kb           *knowledge.KnowledgeBase
+	defaultLimit int
+	maxLimit     int
+}
+
+// NewKnowledgeSearch creates a tool that lets the model search a knowledge base.
+// The tool returns a JSON list of hits with their IDs and scores so the model can cite them.
+// Give each knowledge base its own name and description to attach several to one agent.
+func NewKnowledgeSearch(name, description string, kb *knowledge.KnowledgeBase, opts ...KnowledgeSearchOption) (*Tool, error) {
+	if kb == nil {
+		return nil, fmt.Errorf("knowledge base is required")
+	}
+
+	s := &knowledgeSearch{
+		kb:           kb,
+		defaultLimit: 5,
+		maxLimit:     20,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return New(name, description, s.search)
+}
+
+func (s *knowledgeSearch) search(ctx context.Context, args KnowledgeSearchArgs) (string, error) {
+	if args.Query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = s.defaultLimit
+	}
+	if s.maxLimit > 0 && limit > s.maxLimit {
+		limit = s.maxLimit
+	}
+
+	docs, err := s.kb.RetrieveWithFilter(ctx, args.Query, limit, knowledge.Filter(args.Filter))
+	if err != nil {
+		return "", fmt.Errorf("failed to search knowledge base: %w", err)
+	}
+
+	knowledge.RecordRetrieved(ctx, docs...)
+
+	results := make([]KnowledgeSearchResult, len(docs))
+	for i, doc := range docs {
+		results[i] = KnowledgeSearchResult{
+			ID:       doc.ID,
+			Score:    doc.Score,
+			Content:  doc.Content,
+			Metadata: doc.Metadata,
+		}
+	}
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal search results: %w", err)
+	}
+	return string(b), nil
+}
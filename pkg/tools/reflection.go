@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -18,8 +19,10 @@ type Tool struct {
 }
 
 // New creates a new Tool from a function.
-// The function must take exactly one argument, which must be a struct (or pointer to struct).
+// The function must take exactly one argument, which must be a struct (or pointer to struct),
+// optionally preceded by a context.Context.
 // The struct fields should have `json` tags for names and `description` tags for descriptions.
+// Fields tagged `omitempty` are optional.
 // The function must return (string, error) or just error.
 func New(name string, description string, fn interface{}) (*Tool, error) {
 	def, err := generateDefinition(name, description, fn)
@@ -37,11 +40,18 @@ func New(name string, description string, fn interface{}) (*Tool, error) {
 
 // Call executes the tool with the given arguments (JSON string).
 func (t *Tool) Call(argsJSON string) (string, error) {
+	return t.CallContext(context.Background(), argsJSON)
+}
+
+// CallContext executes the tool with the given arguments (JSON string).
+// The context is passed to functions that accept one.
+func (t *Tool) CallContext(ctx context.Context, argsJSON string) (string, error) {
 	fnVal := reflect.ValueOf(t.Function)
 	fnType := fnVal.Type()
+	withCtx := takesContext(fnType)
 
 	// Create the argument struct
-	argType := fnType.In(0)
+	argType := fnType.In(fnType.NumIn() - 1)
 	isPtr := false
 	if argType.Kind() == reflect.Ptr {
 		argType = argType.Elem()
@@ -57,10 +67,13 @@ func (t *Tool) Call(argsJSON string) (string, error) {
 
 	// Call the function
 	var args []reflect.Value
+	if withCtx {
+		args = append(args, reflect.ValueOf(ctx))
+	}
 	if isPtr {
-		args = []reflect.Value{argVal}
+		args = append(args, argVal)
 	} else {
-		args = []reflect.Value{argVal.Elem()}
+		args = append(args, argVal.Elem())
 	}
 
 	results := fnVal.Call(args)
@@ -94,11 +107,15 @@ func generateDefinition(name, description string, fn interface{}) (*llm.ToolDefi
 		return nil, fmt.Errorf("expected a function, got %s", t.Kind())
 	}
 
-	if t.NumIn() != 1 {
-		return nil, fmt.Errorf("function must have exactly one argument")
+	numArgs := 1
+	if takesContext(t) {
+		numArgs = 2
+	}
+	if t.NumIn() != numArgs {
+		return nil, fmt.Errorf("function must have exactly one argument, optionally preceded by a context")
 	}
 
-	argType := t.In(0)
+	argType := t.In(t.NumIn() - 1)
 	if argType.Kind() == reflect.Ptr {
 		argType = argType.Elem()
 	}
@@ -124,12 +141,19 @@ func generateDefinition(name, description string, fn interface{}) (*llm.ToolDefi
 		prop := map[string]interface{}{
 			"type": goTypeToJSONType(field.Type),
 		}
+		if k := field.Type.Kind(); k == reflect.Slice || k == reflect.Array {
+			prop["items"] = map[string]interface{}{
+				"type": goTypeToJSONType(field.Type.Elem()),
+			}
+		}
 		if descTag != "" {
 			prop["description"] = descTag
 		}
 
 		properties[fieldName] = prop
-		required = append(required, fieldName)
+		if !hasOption(parts[1:], "omitempty") {
+			required = append(required, fieldName)
+		}
 	}
 
 	params := map[string]interface{}{
@@ -158,7 +182,27 @@ func goTypeToJSONType(t reflect.Type) string {
 		return "number"
 	case reflect.Bool:
 		return "boolean"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
 	default:
 		return "string" // Fallback
 	}
 }
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// takesContext reports whether the function's first argument is a context.Context.
+func takesContext(t reflect.Type) bool {
+	return t.NumIn() == 2 && t.In(0) == contextType
+}
+
+func hasOption(options []string, name string) bool {
+	for _, o := range options {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
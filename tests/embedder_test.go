@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/cache"
+)
+
+type countingEmbedder struct {
+	mu       sync.Mutex
+	calls    int
+	texts    int
+	failures int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.failures > 0 {
+		c.failures--
+		return nil, errors.New("rate limited")
+	}
+	c.texts += len(texts)
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestBatchEmbedder(t *testing.T) {
+	inner := &countingEmbedder{failures: 1}
+	embedder := knowledge.NewBatchEmbedder(inner,
+		knowledge.WithBatchSize(2),
+		knowledge.WithConcurrency(1),
+		knowledge.WithRetries(1, time.Millisecond),
+		knowledge.WithCache(knowledge.NewMemoryCache(), "test-model"),
+	)
+
+	texts := []string{"a", "bb", "ccc", "bb", "dddd"}
+	vectors, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	for i, text := range texts {
+		if vectors[i][0] != float32(len(text)) {
+			t.Errorf("Vector %d: expected %d, got %v", i, len(text), vectors[i][0])
+		}
+	}
+	// 4 unique texts in 2 batches, plus one failed attempt.
+	if inner.calls != 3 || inner.texts != 4 {
+		t.Errorf("Expected 3 calls embedding 4 texts, got %d calls and %d texts", inner.calls, inner.texts)
+	}
+
+	if _, err := embedder.Embed(context.Background(), texts); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("Expected cached vectors on second run, got %d calls", inner.calls)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	c, err := cache.NewFile(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFile failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, ok, _ := c.Get(ctx, "model:abc"); ok {
+		t.Fatal("Expected cache miss")
+	}
+	if err := c.Set(ctx, "model:abc", []float32{0.5, -1}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	vec, ok, err := c.Get(ctx, "model:abc")
+	if err != nil || !ok || len(vec) != 2 || vec[0] != 0.5 || vec[1] != -1 {
+		t.Errorf("Unexpected cache entry: %v %v %v", vec, ok, err)
+	}
+}
+
+func TestFileCache_NamespaceWithSlash(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.NewFile(dir)
+	if err != nil {
+		t.Fatalf("NewFile failed: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"openai/text-embedding-3-small:abc", "../escape:abc"} {
+		if err := c.Set(ctx, key, []float32{1}); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+		if vec, ok, err := c.Get(ctx, key); err != nil || !ok || len(vec) != 1 {
+			t.Errorf("Unexpected cache entry for %q: %v %v %v", key, vec, ok, err)
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dir))
+	if len(entries) != 1 {
+		t.Errorf("Expected the cache to stay inside its directory, found %d entries next to it", len(entries))
+	}
+}
+
+func TestMemoryCache_CopiesVectors(t *testing.T) {
+	ctx := context.Background()
+	c := knowledge.NewMemoryCache()
+
+	vec := []float32{1, 2}
+	_ = c.Set(ctx, "key", vec)
+	vec[0] = 9
+	got, _, _ := c.Get(ctx, "key")
+	got[1] = 9
+
+	if again, _, _ := c.Get(ctx, "key"); again[0] != 1 || again[1] != 2 {
+		t.Errorf("Expected the cached vector to be unaffected by callers, got %v", again)
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/encrypt"
+	"github.com/barekit/talos/pkg/memory/inmemory"
+	"github.com/barekit/talos/pkg/memory/memorytest"
+	"github.com/barekit/talos/pkg/memory/sqlite"
+)
+
+func testKey(id string, b byte) encrypt.Key {
+	return encrypt.Key{ID: id, Material: bytes.Repeat([]byte{b}, 32)}
+}
+
+func TestEncrypt_Conformance(t *testing.T) {
+	keys := encrypt.NewKeyRing()
+	if err := keys.Add("", testKey("k1", 1)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	m, err := sqlite.New(filepath.Join(t.TempDir(), "encrypted.db") + "?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("sqlite.New failed: %v", err)
+	}
+	memorytest.Run(t, func(t *testing.T) memory.Memory {
+		return encrypt.New(m, keys)
+	})
+}
+
+func TestEncrypt_AtRest(t *testing.T) {
+	ctx := memory.WithTenant(context.Background(), "acme")
+	keys := encrypt.NewKeyRing()
+	_ = keys.Add("acme", testKey("acme-1", 1))
+	_ = keys.Add("", testKey("default-1", 2))
+
+	inner := inmemory.New()
+	m := encrypt.New(inner, keys)
+
+	msg := llm.Message{
+		Role:        llm.RoleAssistant,
+		Content:     "my card is 4111 1111 1111 1111",
+		ToolCalls:   []llm.ToolCall{{ID: "c1", Function: llm.Function{Name: "charge", Arguments: `{"amount":10}`}}},
+		Attachments: []llm.Attachment{{Type: "text_file", Data: "secret notes"}},
+	}
+	if err := m.Save(ctx, "s1", msg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if msg.ToolCalls[0].Function.Arguments != `{"amount":10}` {
+		t.Error("Save modified the caller's message")
+	}
+
+	stored, _ := inner.Load(ctx, "s1")
+	raw := stored[0]
+	if !strings.HasPrefix(raw.Content, "enc:v1:acme-1:") || strings.Contains(raw.ToolCalls[0].Function.Arguments, "amount") ||
+		strings.Contains(raw.Attachments[0].Data, "secret") || raw.ToolCalls[0].Function.Name != "charge" {
+		t.Errorf("Expected encrypted content at rest, got %+v", raw)
+	}
+
+	// Rotation: new messages use the new key, old ones stay readable.
+	if err := keys.Rotate("acme", testKey("acme-2", 3)); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	_ = m.Save(ctx, "s1", llm.Message{Role: llm.RoleUser, Content: "after rotation"})
+	// Plaintext written before encryption was enabled is passed through.
+	_ = inner.Save(ctx, "s1", llm.Message{Role: llm.RoleUser, Content: "legacy plaintext"})
+
+	msgs, err := m.Load(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(msgs) != 3 || msgs[0].Content != msg.Content || msgs[0].ToolCalls[0].Function.Arguments != `{"amount":10}` ||
+		msgs[0].Attachments[0].Data != "secret notes" || msgs[1].Content != "after rotation" || msgs[2].Content != "legacy plaintext" {
+		t.Errorf("Unexpected decrypted messages: %+v", msgs)
+	}
+	if stored, _ := inner.Load(ctx, "s1"); !strings.HasPrefix(stored[1].Content, "enc:v1:acme-2:") {
+		t.Errorf("Expected the rotated key to be used, got %q", stored[1].Content)
+	}
+
+	// Ciphertext copied into another session does not decrypt.
+	_ = inner.Save(ctx, "s2", raw)
+	if _, err := m.Load(ctx, "s2"); err == nil {
+		t.Error("Expected ciphertext moved to another session to fail")
+	}
+
+	// A tenant without keys of its own falls back to the default keys, and cannot read acme's keys.
+	other := memory.WithTenant(context.Background(), "globex")
+	_ = m.Save(other, "s1", llm.Message{Role: llm.RoleUser, Content: "hello"})
+	if stored, _ := inner.Load(other, "s1"); !strings.HasPrefix(stored[0].Content, "enc:v1:default-1:") {
+		t.Errorf("Expected the default key, got %q", stored[0].Content)
+	}
+	_ = inner.Save(other, "s3", raw)
+	if _, err := m.Load(other, "s3"); !errors.Is(err, encrypt.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestEncrypt_TenantKeyAddedLater(t *testing.T) {
+	ctx := memory.WithTenant(context.Background(), "globex")
+	keys := encrypt.NewKeyRing()
+	_ = keys.Add("", testKey("default-1", 1))
+	m := encrypt.New(inmemory.New(), keys)
+
+	// Written with the default key while the tenant had none of its own.
+	_ = m.Save(ctx, "s1", llm.Message{Role: llm.RoleUser, Content: "before"})
+	if err := keys.Add("globex", testKey("globex-1", 2)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	_ = m.Save(ctx, "s1", llm.Message{Role: llm.RoleUser, Content: "after"})
+
+	msgs, err := m.Load(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Content != "before" || msgs[1].Content != "after" {
+		t.Errorf("Unexpected decrypted messages: %+v", msgs)
+	}
+}
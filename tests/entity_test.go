@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/agent"
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/entity"
+)
+
+func TestEntity_Parse(t *testing.T) {
+	g, err := entity.Parse("```json\n" + `{"entities":[{"name":" Alice ","type":"Person"},{"name":""}],
+		"relations":[{"source":"Alice","target":"Acme","type":"works at"},{"source":"Alice","target":"","type":"KNOWS"}]}` + "\n```")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(g.Entities) != 1 || g.Entities[0].Name != "Alice" || g.Entities[0].Type != "person" {
+		t.Errorf("Unexpected entities: %+v", g.Entities)
+	}
+	if len(g.Relations) != 1 || g.Relations[0].Type != "WORKS_AT" {
+		t.Errorf("Unexpected relations: %+v", g.Relations)
+	}
+}
+
+func TestEntity_InMemoryStore(t *testing.T) {
+	ctx := memory.WithTenant(context.Background(), "acme")
+	store := entity.NewInMemoryStore()
+
+	err := store.UpsertGraph(ctx, "s1", entity.Graph{
+		Entities: []entity.Entity{
+			{Name: "Alice", Type: "person", Properties: map[string]string{"role": "CFO"}},
+			{Name: "Al", Type: "person"},
+		},
+		Relations: []entity.Relation{
+			{Source: "Alice", Target: "Acme Corp", Type: "WORKS_AT"},
+			{Source: "Bob", Target: "Globex", Type: "WORKS_AT"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpsertGraph failed: %v", err)
+	}
+	// Later turns merge properties into existing entities.
+	_ = store.UpsertGraph(ctx, "s2", entity.Graph{Entities: []entity.Entity{{Name: "alice", Properties: map[string]string{"city": "Berlin"}}}})
+
+	g, err := store.RecallGraph(ctx, "What did ALICE ask for?", 10)
+	if err != nil {
+		t.Fatalf("RecallGraph failed: %v", err)
+	}
+	if len(g.Entities) != 2 || g.Entities[0].Name != "Acme Corp" || g.Entities[1].Properties["city"] != "Berlin" || g.Entities[1].Properties["role"] != "CFO" {
+		t.Errorf("Unexpected recalled entities: %+v", g.Entities)
+	}
+	if len(g.Relations) != 1 || g.Relations[0].Target != "Acme Corp" {
+		t.Errorf("Unexpected recalled relations: %+v", g.Relations)
+	}
+
+	if g, _ := store.RecallGraph(memory.WithTenant(context.Background(), "other"), "alice", 10); !g.Empty() {
+		t.Errorf("Expected tenants to be isolated, got %+v", g)
+	}
+}
+
+func TestEntity_Candidates(t *testing.T) {
+	candidates := entity.Candidates("Did Acme Corp's CFO, Alice, email (bob)?")
+	for _, want := range []string{"acme corp", "acme", "alice", "bob", "cfo", "acme corp's cfo"} {
+		found := false
+		for _, c := range candidates {
+			found = found || c == want
+		}
+		if !found {
+			t.Errorf("Expected candidate %q in %q", want, candidates)
+		}
+	}
+	for _, c := range candidates {
+		if c == "lice" || c == "corp'" || strings.HasPrefix(c, " ") {
+			t.Errorf("Unexpected candidate %q", c)
+		}
+	}
+}
+
+func TestEntity_RecallRanksByRelevance(t *testing.T) {
+	ctx := context.Background()
+	store := entity.NewInMemoryStore()
+	_ = store.UpsertGraph(ctx, "", entity.Graph{Entities: []entity.Entity{{Name: "Alice"}, {Name: "Bob"}, {Name: "Carol"}}})
+
+	g, err := store.RecallGraph(ctx, "Carol met Bob. Bob said hi to Alice and Bob.", 1)
+	if err != nil {
+		t.Fatalf("RecallGraph failed: %v", err)
+	}
+	if len(g.Entities) != 1 || g.Entities[0].Name != "Bob" {
+		t.Errorf("Expected the most mentioned entity, got %+v", g.Entities)
+	}
+}
+
+func TestEntity_Agent(t *testing.T) {
+	provider := &recordingProvider{mockProvider: mockProvider{responses: []llm.Message{
+		{Role: llm.RoleAssistant, Content: "Noted, I will prepare the invoice for Alice."},
+		{Role: llm.RoleAssistant, Content: `{"entities":[{"name":"Alice","type":"person"}],
+			"relations":[{"source":"Alice","target":"Acme","type":"WORKS_AT"},{"source":"Alice","target":"March invoice","type":"ASKED_FOR"}]}`},
+		{Role: llm.RoleAssistant, Content: "She asked for the March invoice."},
+	}}}
+
+	em := entity.New(entity.NewInMemoryStore(), provider)
+	a := agent.New(provider, agent.WithEntityMemory(em))
+
+	if _, err := a.Run(context.Background(), "Alice from Acme needs the March invoice", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	a.History = nil
+	if _, err := a.Run(context.Background(), "What did Alice ask for?", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Calls: first answer, extraction, second answer.
+	var facts string
+	for _, msg := range provider.seen[2] {
+		if msg.Role == llm.RoleSystem {
+			facts = msg.Content
+		}
+	}
+	if !strings.Contains(facts, "Alice asked for March invoice") || !strings.Contains(facts, "Alice works at Acme") {
+		t.Errorf("Expected recalled facts in the prompt, got %q", facts)
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/eval"
+)
+
+func TestEvalMetrics(t *testing.T) {
+	retrieved := []string{"x", "a", "y", "b"}
+	relevant := eval.Relevance{"a": 1, "b": 1, "c": 1}
+
+	if got := eval.RecallAtK(retrieved, relevant, 2); math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("recall@2: got %v", got)
+	}
+	if got := eval.PrecisionAtK(retrieved, relevant, 4); got != 0.5 {
+		t.Errorf("precision@4: got %v", got)
+	}
+	if got := eval.ReciprocalRank(retrieved, relevant); got != 0.5 {
+		t.Errorf("rr: got %v", got)
+	}
+	if got := eval.NDCGAtK([]string{"a", "b", "c"}, relevant, 3); math.Abs(got-1) > 1e-9 {
+		t.Errorf("ndcg@3 of a perfect ranking: got %v", got)
+	}
+
+	// Documents judged not relevant and repeated results do not count.
+	judged := eval.Relevance{"a": 1, "b": 1, "n": 0}
+	if got := eval.RecallAtK([]string{"a", "b"}, judged, 2); got != 1 {
+		t.Errorf("recall@2 with a nonrelevant label: got %v", got)
+	}
+	if got := eval.RecallAtK([]string{"a", "a", "a"}, eval.Relevance{"a": 1, "b": 1}, 3); got != 0.5 {
+		t.Errorf("recall@3 with duplicates: got %v", got)
+	}
+}
+
+func TestEvalRun(t *testing.T) {
+	queries, err := eval.LoadQueries(strings.NewReader(`{"id": "q1", "text": "where is chat history stored", "relevant": ["memory"]}
+{"id": "q2", "text": "vector stores", "relevant": ["rag"]}`))
+	if err != nil {
+		t.Fatalf("LoadQueries failed: %v", err)
+	}
+
+	kb := newLocalKnowledge(t)
+	empty := eval.RetrieverFunc(func(ctx context.Context, query string, limit int) ([]knowledge.Document, error) {
+		return nil, nil
+	})
+
+	report, err := eval.Run(context.Background(), queries, []eval.Config{
+		{Name: "local", Retriever: kb},
+		{Name: "empty", Retriever: empty},
+	}, []int{1, 3})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Configs[0].MRR != 1 || report.Configs[0].AtK[0].Recall != 1 {
+		t.Errorf("Expected perfect local retrieval, got %+v", report.Configs[0])
+	}
+	if report.Configs[1].MRR != 0 {
+		t.Errorf("Expected zero MRR for empty retriever, got %v", report.Configs[1].MRR)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "recall@3") {
+		t.Errorf("Expected recall@3 column, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/barekit/talos/pkg/agent"
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/local"
+	"github.com/barekit/talos/pkg/llm"
+)
+
+func newLocalKnowledge(t *testing.T) *knowledge.KnowledgeBase {
+	t.Helper()
+
+	embedder, err := local.NewEmbedder(256)
+	if err != nil {
+		t.Fatalf("NewEmbedder failed: %v", err)
+	}
+
+	kb := knowledge.NewKnowledgeBase(embedder, local.NewStore())
+	docs := []knowledge.Document{
+		{ID: "go", Content: "Talos is an agent framework written in Go.", Metadata: map[string]interface{}{"topic": "intro"}},
+		{ID: "memory", Content: "Chat history can be stored in SQLite, Postgres, Redis or MongoDB.", Metadata: map[string]interface{}{"topic": "memory"}},
+		{ID: "rag", Content: "Vector stores include Qdrant and pgvector.", Metadata: map[string]interface{}{"topic": "rag"}},
+	}
+	if err := kb.Ingest(context.Background(), docs); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	return kb
+}
+
+func TestLocalEmbedder_Deterministic(t *testing.T) {
+	embedder, _ := local.NewEmbedder(64)
+	a, _ := embedder.Embed(context.Background(), []string{"Hello, World"})
+	b, _ := embedder.Embed(context.Background(), []string{"hello world"})
+
+	if sim := knowledge.CosineSimilarity(a[0], b[0]); sim < 0.999 {
+		t.Errorf("Expected identical vectors after normalization, got similarity %v", sim)
+	}
+}
+
+func TestLocalKnowledge_Retrieve(t *testing.T) {
+	kb := newLocalKnowledge(t)
+
+	docs, err := kb.Retrieve(context.Background(), "Where is chat history stored?", 1)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "memory" {
+		t.Fatalf("Expected memory document, got %+v", docs)
+	}
+
+	docs, err = kb.RetrieveWithFilter(context.Background(), "Where is chat history stored?", 3, knowledge.Filter{"topic": "rag"})
+	if err != nil {
+		t.Fatalf("RetrieveWithFilter failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "rag" {
+		t.Errorf("Expected only the rag document, got %+v", docs)
+	}
+}
+
+func TestAgent_LocalRAG(t *testing.T) {
+	a := agent.New(&mockProvider{
+		responses: []llm.Message{{Role: llm.RoleAssistant, Content: "Qdrant and pgvector."}},
+	}, agent.WithKnowledge(newLocalKnowledge(t)), agent.WithRAGOptions(agent.RAGOptions{TopK: 1}))
+
+	result, err := a.RunResult(context.Background(), "Which vector stores are supported?", nil)
+	if err != nil {
+		t.Fatalf("RunResult failed: %v", err)
+	}
+	if len(result.Citations) != 1 || result.Citations[0].DocumentID != "rag" {
+		t.Errorf("Expected a citation for the rag document, got %+v", result.Citations)
+	}
+}
@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+	_ "github.com/barekit/talos/pkg/memory/all"
+	gormmem "github.com/barekit/talos/pkg/memory/gorm"
+	"github.com/barekit/talos/pkg/memory/inmemory"
+	"github.com/barekit/talos/pkg/memory/memorytest"
+	"github.com/barekit/talos/pkg/memory/sqlite"
+	"github.com/google/uuid"
+	gormsqlite "gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMemory_InMemory(t *testing.T) {
+	memorytest.Run(t, func(t *testing.T) memory.Memory {
+		return inmemory.New()
+	})
+}
+
+func TestMemory_SQLite(t *testing.T) {
+	memorytest.Run(t, func(t *testing.T) memory.Memory {
+		// A file database so concurrent connections share it; busy_timeout serializes writers.
+		dsn := filepath.Join(t.TempDir(), "memory.db") + "?_busy_timeout=5000"
+		m, err := sqlite.New(dsn)
+		if err != nil {
+			t.Fatalf("sqlite.New failed: %v", err)
+		}
+		return m
+	})
+}
+
+// TestMemory_External runs the suite against every backend whose DSN is set in the environment.
+func TestMemory_External(t *testing.T) {
+	backends := []struct {
+		typ memory.Type
+		env string
+	}{
+		{memory.TypePostgres, "TALOS_TEST_POSTGRES_DSN"},
+		{memory.TypeMySQL, "TALOS_TEST_MYSQL_DSN"},
+		{memory.TypeMSSQL, "TALOS_TEST_MSSQL_DSN"},
+		{memory.TypeRedis, "TALOS_TEST_REDIS_URL"},
+		{memory.TypeMongo, "TALOS_TEST_MONGO_URI"},
+		{memory.TypeNeo4j, "TALOS_TEST_NEO4J_URI"},
+	}
+
+	for _, backend := range backends {
+		t.Run(string(backend.typ), func(t *testing.T) {
+			dsn := os.Getenv(backend.env)
+			if dsn == "" {
+				t.Skipf("Skipping %s memory tests: %s not set", backend.typ, backend.env)
+			}
+
+			m, err := memory.NewFactory(context.Background(), memory.Config{
+				Type:             backend.typ,
+				ConnectionString: dsn,
+				Username:         os.Getenv("TALOS_TEST_NEO4J_USERNAME"),
+				Password:         os.Getenv("TALOS_TEST_NEO4J_PASSWORD"),
+			})
+			if err != nil {
+				t.Fatalf("NewFactory failed: %v", err)
+			}
+			memorytest.Run(t, func(t *testing.T) memory.Memory { return m })
+		})
+	}
+}
+
+func TestMemoryFactory_Scheme(t *testing.T) {
+	dsn := "sqlite://" + filepath.Join(t.TempDir(), "factory.db")
+	m, err := memory.NewFactory(context.Background(), memory.Config{ConnectionString: dsn})
+	if err != nil {
+		t.Fatalf("NewFactory failed: %v", err)
+	}
+	if err := m.Save(context.Background(), "s", llm.Message{Role: llm.RoleUser, Content: "hi"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if _, err := memory.NewFactory(context.Background(), memory.Config{ConnectionString: "unknown://x"}); err == nil {
+		t.Errorf("Expected error for unregistered scheme")
+	}
+	if _, err := memory.NewFactory(context.Background(), memory.Config{ConnectionString: "talos.db"}); err == nil {
+		t.Errorf("Expected error without type or scheme")
+	}
+}
+
+func TestMemoryFactory_Register(t *testing.T) {
+	// A fresh name per run, since drivers cannot be registered twice.
+	name := "custom-" + uuid.NewString()[:8]
+	custom := inmemory.New()
+	memory.Register(memory.Type(name), func(ctx context.Context, cfg memory.Config) (memory.Memory, error) {
+		return custom, nil
+	}, name)
+
+	m, err := memory.NewFactory(context.Background(), memory.Config{ConnectionString: name + "://anything"})
+	if err != nil {
+		t.Fatalf("NewFactory failed: %v", err)
+	}
+	if m != custom {
+		t.Errorf("Expected the registered driver to be used")
+	}
+}
+
+func TestMemory_SQLiteSweep(t *testing.T) {
+	ctx := context.Background()
+	m, err := sqlite.New(filepath.Join(t.TempDir(), "sweep.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New failed: %v", err)
+	}
+
+	msg := llm.Message{Role: llm.RoleUser, Content: "hello"}
+	_ = m.Save(ctx, "idle", msg)
+	_ = m.Save(ctx, "idle", msg)
+	time.Sleep(10 * time.Millisecond)
+	cutoff := time.Now()
+	_ = m.Save(ctx, "active", msg)
+
+	deleted, err := m.Sweep(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted messages, got %d", deleted)
+	}
+
+	if msgs, _ := m.Load(ctx, "idle"); len(msgs) != 0 {
+		t.Errorf("Expected idle session to be swept, got %d messages", len(msgs))
+	}
+	if msgs, _ := m.Load(ctx, "active"); len(msgs) != 1 {
+		t.Errorf("Expected active session to be kept, got %d messages", len(msgs))
+	}
+}
+
+func TestMemory_SQLiteLegacyRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "legacy.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open failed: %v", err)
+	}
+	m, err := gormmem.New(db)
+	if err != nil {
+		t.Fatalf("gormmem.New failed: %v", err)
+	}
+
+	// Rows written before branching support have no parent and no session head.
+	for _, content := range []string{"one", "two"} {
+		if err := db.Create(&gormmem.MessageModel{SessionID: "legacy", Role: "user", Content: content}).Error; err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if err := m.Save(ctx, "legacy", llm.Message{Role: llm.RoleUser, Content: "three"}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	msgs, err := m.Load(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(msgs) != 3 || msgs[0].Content != "one" || msgs[2].Content != "three" {
+		t.Errorf("Expected legacy rows followed by the new message, got %+v", msgs)
+	}
+
+	// Legacy rows have no sequence number; numbering starts with the first new message.
+	history, err := m.History(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 3 || history[0].Seq != 0 || history[1].Seq != 0 || history[2].Seq != 1 {
+		t.Errorf("Unexpected sequence numbers: %+v", history)
+	}
+}
+
+func TestMemory_SQLiteBackfillsSessions(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "legacy.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open failed: %v", err)
+	}
+	if _, err := gormmem.New(db); err != nil {
+		t.Fatalf("gormmem.New failed: %v", err)
+	}
+
+	// Sessions written before session records existed only have message rows.
+	if err := db.Create(&gormmem.MessageModel{SessionID: "legacy", Role: "user", Content: "hello"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	m, err := gormmem.New(db)
+	if err != nil {
+		t.Fatalf("gormmem.New failed: %v", err)
+	}
+	sessions, err := m.ListSessions(ctx, memory.SessionQuery{})
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "legacy" || sessions[0].UpdatedAt.IsZero() {
+		t.Errorf("Expected the legacy session to be backfilled, got %+v", sessions)
+	}
+}
+
+func TestMemory_SQLiteSweepOrphans(t *testing.T) {
+	ctx := context.Background()
+	db, err := gorm.Open(gormsqlite.Open(filepath.Join(t.TempDir(), "orphans.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open failed: %v", err)
+	}
+	m, err := gormmem.New(db)
+	if err != nil {
+		t.Fatalf("gormmem.New failed: %v", err)
+	}
+
+	// Rows inserted after the backfill ran have no session record.
+	if err := db.Create(&gormmem.MessageModel{SessionID: "orphan", Role: "user", Content: "hello"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	cutoff := time.Now()
+	_ = m.Save(ctx, "active", llm.Message{Role: llm.RoleUser, Content: "hello"})
+
+	deleted, err := m.Sweep(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("Sweep failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted message, got %d", deleted)
+	}
+	if msgs, _ := m.Load(ctx, "orphan"); len(msgs) != 0 {
+		t.Errorf("Expected orphaned messages to be swept, got %d", len(msgs))
+	}
+	if msgs, _ := m.Load(ctx, "active"); len(msgs) != 1 {
+		t.Errorf("Expected active session to be kept, got %d messages", len(msgs))
+	}
+}
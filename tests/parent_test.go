@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/local"
+)
+
+func TestKnowledgeBase_ParentRetrieval(t *testing.T) {
+	embedder, _ := local.NewEmbedder(256)
+	kb := knowledge.NewParentKnowledgeBase(embedder, local.NewStore(), knowledge.NewMemoryDocumentStore())
+
+	manual := "Installation requires Go 1.24. " +
+		"Configure the database connection string in the environment. " +
+		"Backups run nightly and are kept for thirty days."
+	faq := "Pricing is per seat. Discounts apply to annual plans."
+
+	err := kb.IngestParents(context.Background(), []knowledge.Document{
+		{ID: "manual", Content: manual, Metadata: map[string]interface{}{"kind": "manual"}},
+		{ID: "faq", Content: faq, Metadata: map[string]interface{}{"kind": "faq"}},
+	}, knowledge.SplitBySize(40, 0))
+	if err != nil {
+		t.Fatalf("IngestParents failed: %v", err)
+	}
+
+	docs, err := kb.Retrieve(context.Background(), "how long are backups kept", 2)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0].ID != "manual" || docs[0].Content != manual {
+		t.Fatalf("Expected the full manual first, got %+v", docs)
+	}
+	if docs[1].ID != "faq" {
+		t.Errorf("Expected parents to be deduplicated, got %+v", docs)
+	}
+
+	docs, err = kb.RetrieveWithFilter(context.Background(), "backups", 2, knowledge.Filter{"kind": "faq"})
+	if err != nil {
+		t.Fatalf("RetrieveWithFilter failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "faq" {
+		t.Errorf("Expected only the faq parent, got %+v", docs)
+	}
+}
+
+func TestKnowledgeBase_IngestParentsShrink(t *testing.T) {
+	ctx := context.Background()
+	embedder, _ := local.NewEmbedder(64)
+	store := local.NewStore()
+	kb := knowledge.NewParentKnowledgeBase(embedder, store, knowledge.NewMemoryDocumentStore())
+	splitter := knowledge.SplitBySize(10, 0)
+
+	long := knowledge.Document{ID: "doc", Content: "aaaaaaaaa bbbbbbbbb ccccccccc ddddddddd"}
+	if err := kb.IngestParents(ctx, []knowledge.Document{long}, splitter); err != nil {
+		t.Fatalf("IngestParents failed: %v", err)
+	}
+	short := knowledge.Document{ID: "doc", Content: "aaaaaaaaa          "}
+	if err := kb.IngestParents(ctx, []knowledge.Document{short}, splitter); err != nil {
+		t.Fatalf("IngestParents failed: %v", err)
+	}
+
+	vectors, _ := embedder.Embed(ctx, []string{"aaaaaaaaa"})
+	children, err := store.Search(ctx, vectors[0], 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != "doc#0" {
+		t.Errorf("Expected stale and whitespace-only children to be gone, got %+v", children)
+	}
+}
+
+func TestKnowledgeBase_ParentMetadataUnchanged(t *testing.T) {
+	ctx := context.Background()
+	embedder, _ := local.NewEmbedder(64)
+	store := local.NewStore()
+	kb := knowledge.NewParentKnowledgeBase(embedder, store, knowledge.NewMemoryDocumentStore())
+
+	parent := knowledge.Document{
+		ID:       "family",
+		Content:  "Alice has three children.",
+		Metadata: map[string]interface{}{"children": "3"},
+	}
+	if err := kb.IngestParents(ctx, []knowledge.Document{parent}, knowledge.SplitBySize(100, 0)); err != nil {
+		t.Fatalf("IngestParents failed: %v", err)
+	}
+
+	docs, err := kb.Retrieve(ctx, "children", 1)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(docs) != 1 || len(docs[0].Metadata) != 1 || docs[0].Metadata["children"] != "3" {
+		t.Errorf("Expected the parent metadata unchanged, got %+v", docs)
+	}
+
+	vectors, _ := embedder.Embed(ctx, []string{"children"})
+	children, _ := store.Search(ctx, vectors[0], 1)
+	if len(children) != 1 || children[0].Metadata["children"] != "3" {
+		t.Errorf("Expected children to keep the user metadata, got %+v", children)
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	pgstore "github.com/barekit/talos/pkg/knowledge/postgres"
+	gormpostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a gorm logger that records every statement.
+type sqlRecorder struct {
+	logger.Interface
+	mu  sync.Mutex
+	sql []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sql = append(r.sql, sql)
+}
+
+func (r *sqlRecorder) contains(substr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sql := range r.sql {
+		if strings.Contains(sql, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDryRunPostgres returns a PostgresStore that records its SQL instead of running it.
+func newDryRunPostgres(t *testing.T, opts ...pgstore.Option) (*pgstore.PostgresStore, *sqlRecorder) {
+	t.Helper()
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(gormpostgres.New(gormpostgres.Config{DSN: "host=localhost dbname=talos"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               recorder,
+	})
+	if err != nil {
+		t.Fatalf("gorm.Open failed: %v", err)
+	}
+	store, err := pgstore.NewWithDB(db, opts...)
+	if err != nil {
+		t.Fatalf("NewWithDB failed: %v", err)
+	}
+	return store, recorder
+}
+
+func TestPostgresStore_Schema(t *testing.T) {
+	_, recorder := newDryRunPostgres(t,
+		pgstore.WithTableName("kb_docs"),
+		pgstore.WithDimension(768),
+		pgstore.WithDistance(pgstore.DistanceL2),
+		pgstore.WithHNSWIndex(pgstore.HNSWParams{M: 16, EfConstruction: 64}),
+	)
+	if !recorder.contains(`CREATE TABLE IF NOT EXISTS "kb_docs"`) || !recorder.contains("embedding vector(768)") {
+		t.Errorf("Expected the configured table and dimension, got %v", recorder.sql)
+	}
+	if !recorder.contains(`USING hnsw (embedding vector_l2_ops) WITH (ef_construction = 64, m = 16)`) {
+		t.Errorf("Expected an HNSW index with parameters, got %v", recorder.sql)
+	}
+
+	_, recorder = newDryRunPostgres(t, pgstore.WithIVFFlatIndex(pgstore.IVFFlatParams{}))
+	if !recorder.contains(`USING ivfflat (embedding vector_cosine_ops)`) || recorder.contains(" WITH (") {
+		t.Errorf("Expected unset index parameters to be skipped, got %v", recorder.sql)
+	}
+
+	if _, err := pgstore.NewWithDB(&gorm.DB{}, pgstore.WithTableName("docs; DROP TABLE x")); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+}
+
+func TestPostgresStore_SearchWithFilter(t *testing.T) {
+	store, recorder := newDryRunPostgres(t, pgstore.WithTableName("kb_docs"))
+	_, err := store.SearchWithFilter(context.Background(), []float32{1, 0}, 3, knowledge.Filter{
+		"lang": []interface{}{"go", "rust"},
+	})
+	if err != nil {
+		t.Fatalf("SearchWithFilter failed: %v", err)
+	}
+	if !recorder.contains(`FROM "kb_docs" WHERE (metadata @> '{"lang":"go"}'::jsonb OR metadata @> '{"lang":"rust"}'::jsonb)`) {
+		t.Errorf("Expected a containment filter matching any list item, got %v", recorder.sql)
+	}
+	if recorder.contains("SET LOCAL") {
+		t.Errorf("Expected no search parameters, got %v", recorder.sql)
+	}
+}
+
+func TestPostgresStore_DistanceScore(t *testing.T) {
+	tests := []struct {
+		distance pgstore.Distance
+		value    float64
+		want     float32
+	}{
+		{pgstore.DistanceCosine, 0.25, 0.75},
+		{pgstore.DistanceL2, 1, 0.5},
+		{pgstore.DistanceInnerProduct, -0.8, 0.8},
+	}
+	for _, tt := range tests {
+		if got := tt.distance.Score(tt.value); got != tt.want {
+			t.Errorf("%s: Score(%v) = %v, want %v", tt.distance, tt.value, got, tt.want)
+		}
+	}
+}
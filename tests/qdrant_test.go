@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	qdrantstore "github.com/barekit/talos/pkg/knowledge/qdrant"
+	"github.com/google/uuid"
+)
+
+func TestQdrant_PayloadRoundTrip(t *testing.T) {
+	doc := knowledge.Document{
+		ID:      "guide.md#0",
+		Content: "Talos is written in Go.",
+		Metadata: map[string]interface{}{
+			"page":   3,
+			"score":  0.5,
+			"draft":  false,
+			"source": "guide.md",
+			"tags":   []string{"go", "agents"},
+			"author": map[string]interface{}{"name": "Ada", "id": 7},
+		},
+	}
+
+	payload, err := qdrantstore.ToPayload(doc)
+	if err != nil {
+		t.Fatalf("ToPayload failed: %v", err)
+	}
+	got := qdrantstore.FromPayload(payload)
+
+	want := knowledge.Document{
+		ID:      doc.ID,
+		Content: doc.Content,
+		Metadata: map[string]interface{}{
+			"page":   int64(3),
+			"score":  0.5,
+			"draft":  false,
+			"source": "guide.md",
+			"tags":   []interface{}{"go", "agents"},
+			"author": map[string]interface{}{"name": "Ada", "id": int64(7)},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Round trip mismatch:\ngot  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestQdrant_PayloadReservedKeys(t *testing.T) {
+	for _, key := range []string{qdrantstore.PayloadContent, qdrantstore.PayloadDocumentID} {
+		doc := knowledge.Document{ID: "a", Content: "text", Metadata: map[string]interface{}{key: "x"}}
+		if _, err := qdrantstore.ToPayload(doc); err == nil {
+			t.Errorf("Expected metadata key %q to be rejected", key)
+		}
+	}
+}
+
+func TestQdrant_PointID(t *testing.T) {
+	id := uuid.NewString()
+	if got := qdrantstore.PointID(id); got != id {
+		t.Errorf("Expected UUIDs to be kept, got %s", got)
+	}
+
+	a, b := qdrantstore.PointID("guide.md#0"), qdrantstore.PointID("guide.md#1")
+	if _, err := uuid.Parse(a); err != nil {
+		t.Errorf("Expected a UUID, got %s", a)
+	}
+	if a != qdrantstore.PointID("guide.md#0") || a == b {
+		t.Errorf("Expected deterministic and distinct point IDs, got %s and %s", a, b)
+	}
+}
+
+func TestQdrant_ToFilter(t *testing.T) {
+	filter, err := qdrantstore.ToFilter(knowledge.Filter{"lang": []string{"go", "rust"}})
+	if err != nil {
+		t.Fatalf("ToFilter failed: %v", err)
+	}
+	if len(filter.GetMust()) != 1 {
+		t.Fatalf("Expected one condition, got %d", len(filter.GetMust()))
+	}
+	should := filter.GetMust()[0].GetFilter().GetShould()
+	if len(should) != 2 || should[0].GetField().GetKey() != "lang" ||
+		should[0].GetField().GetMatch().GetKeyword() != "go" {
+		t.Errorf("Unexpected list condition: %v", filter)
+	}
+
+	filter, err = qdrantstore.ToFilter(knowledge.Filter{"page": 3})
+	if err != nil {
+		t.Fatalf("ToFilter failed: %v", err)
+	}
+	if match := filter.GetMust()[0].GetField().GetMatch(); match.GetInteger() != 3 {
+		t.Errorf("Expected an integer match, got %v", match)
+	}
+
+	if _, err := qdrantstore.ToFilter(knowledge.Filter{"page": nil}); err == nil {
+		t.Error("Expected an error for an unsupported filter value")
+	}
+	if filter, _ := qdrantstore.ToFilter(nil); filter != nil {
+		t.Error("Expected no filter for an empty knowledge.Filter")
+	}
+}
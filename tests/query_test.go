@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/query"
+	"github.com/barekit/talos/pkg/llm"
+)
+
+func TestFuse(t *testing.T) {
+	lists := [][]knowledge.Document{
+		{{ID: "a", Score: 0.5}, {ID: "b", Score: 0.4}},
+		{{ID: "b", Score: 0.9}, {ID: "c", Score: 0.3}},
+	}
+
+	docs := query.Fuse(lists, 2)
+	if len(docs) != 2 || docs[0].ID != "b" || docs[1].ID != "a" {
+		t.Fatalf("Unexpected fusion order: %+v", docs)
+	}
+	if docs[0].Score != 0.9 {
+		t.Errorf("Expected best score 0.9 for b, got %v", docs[0].Score)
+	}
+}
+
+func TestFuse_DocumentsWithoutID(t *testing.T) {
+	lists := [][]knowledge.Document{
+		{{Content: "one"}, {Content: "two"}},
+		{{Content: "two"}, {Content: "three"}},
+	}
+
+	docs := query.Fuse(lists, 0)
+	if len(docs) != 3 || docs[0].Content != "two" {
+		t.Errorf("Expected documents without an ID to be fused by content, got %+v", docs)
+	}
+}
+
+func TestMultiQuery(t *testing.T) {
+	mock := &mockProvider{
+		responses: []llm.Message{
+			{Role: llm.RoleAssistant, Content: "1. talos memory backends\n2. which databases store chat history\n3. extra"},
+		},
+	}
+
+	queries, err := query.NewMultiQuery(mock, 2).Transform(context.Background(), "what can store history?", nil)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+
+	expected := []string{"what can store history?", "talos memory backends", "which databases store chat history"}
+	if len(queries) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, queries)
+	}
+	for i := range expected {
+		if queries[i] != expected[i] {
+			t.Errorf("Query %d: expected %q, got %q", i, expected[i], queries[i])
+		}
+	}
+}
+
+func TestRewriter(t *testing.T) {
+	provider := &recordingProvider{mockProvider: mockProvider{responses: []llm.Message{
+		{Role: llm.RoleAssistant, Content: "  Does Talos support Redis?  "},
+	}}}
+	rewriter := query.NewRewriter(provider)
+
+	// Without a conversation there is nothing to resolve.
+	rewritten, err := rewriter.Rewrite(context.Background(), "does it support redis?", nil)
+	if err != nil || rewritten != "does it support redis?" || len(provider.seen) != 0 {
+		t.Fatalf("Expected the query unchanged without a model call, got %q (%v, %d calls)", rewritten, err, len(provider.seen))
+	}
+
+	history := []llm.Message{
+		{Role: llm.RoleSystem, Content: "You are helpful."},
+		{Role: llm.RoleUser, Content: "Tell me about Talos."},
+		{Role: llm.RoleAssistant, Content: "Talos is an agent framework."},
+	}
+	queries, err := rewriter.Transform(context.Background(), "does it support redis?", history)
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if len(queries) != 1 || queries[0] != "Does Talos support Redis?" {
+		t.Errorf("Unexpected rewritten queries: %q", queries)
+	}
+
+	prompt := provider.seen[0][1].Content
+	if !strings.Contains(prompt, "user: Tell me about Talos.") || strings.Contains(prompt, "You are helpful.") {
+		t.Errorf("Expected a transcript of user and assistant messages, got %q", prompt)
+	}
+}
+
+func TestHyDE(t *testing.T) {
+	mock := &mockProvider{responses: []llm.Message{
+		{Role: llm.RoleAssistant, Content: "Talos stores history in Redis, SQL, Mongo or Neo4j."},
+		{Role: llm.RoleAssistant, Content: "Talos stores history in Redis, SQL, Mongo or Neo4j."},
+		{Role: llm.RoleAssistant, Content: "   "},
+	}}
+	hyde := query.NewHyDE(mock)
+
+	queries, err := hyde.Transform(context.Background(), "where is history stored?", nil)
+	if err != nil || len(queries) != 1 || !strings.HasPrefix(queries[0], "Talos stores history") {
+		t.Fatalf("Expected the hypothetical passage only, got %q (%v)", queries, err)
+	}
+
+	hyde.IncludeOriginal = true
+	queries, _ = hyde.Transform(context.Background(), "where is history stored?", nil)
+	if len(queries) != 2 || queries[0] != "where is history stored?" {
+		t.Errorf("Expected the original query and the passage, got %q", queries)
+	}
+
+	hyde.IncludeOriginal = false
+	queries, _ = hyde.Transform(context.Background(), "where is history stored?", nil)
+	if len(queries) != 1 || queries[0] != "where is history stored?" {
+		t.Errorf("Expected the original query when no passage is generated, got %q", queries)
+	}
+}
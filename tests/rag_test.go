@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/agent"
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/query"
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory/inmemory"
+)
+
+type mockEmbedder struct{}
+
+func (m *mockEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+type mockStore struct {
+	docs []knowledge.Document
+}
+
+func (m *mockStore) Upsert(ctx context.Context, vectors [][]float32, documents []knowledge.Document) error {
+	m.docs = append(m.docs, documents...)
+	return nil
+}
+
+func (m *mockStore) Search(ctx context.Context, query []float32, limit int) ([]knowledge.Document, error) {
+	if limit > len(m.docs) {
+		limit = len(m.docs)
+	}
+	return m.docs[:limit], nil
+}
+
+func newMockKnowledge() *knowledge.KnowledgeBase {
+	return knowledge.NewKnowledgeBase(&mockEmbedder{}, &mockStore{
+		docs: []knowledge.Document{
+			{ID: "a", Content: "Talos is written in Go.", Score: 0.9},
+			{ID: "b", Content: "Talos supports Redis.", Score: 0.4},
+		},
+	})
+}
+
+func TestAgent_RAGDefaults(t *testing.T) {
+	mem := inmemory.New()
+	a := agent.New(&mockProvider{}, agent.WithKnowledge(newMockKnowledge()), agent.WithMemory(mem, "s1"))
+
+	if _, err := a.Run(context.Background(), "What is Talos?", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	history, _ := mem.Load(context.Background(), "s1")
+	if !strings.Contains(history[0].Content, "Relevant Context") {
+		t.Errorf("Expected context in saved user message, got %q", history[0].Content)
+	}
+}
+
+func TestAgent_RAGSystemPlacementNotPersisted(t *testing.T) {
+	mem := inmemory.New()
+	a := agent.New(&mockProvider{},
+		agent.WithKnowledge(newMockKnowledge()),
+		agent.WithMemory(mem, "s1"),
+		agent.WithRAGOptions(agent.RAGOptions{
+			ScoreThreshold:     0.5,
+			PromptTemplate:     "{{range .Documents}}[{{.ID}}] {{.Content}}\n{{end}}",
+			Placement:          agent.PlacementSystemMessage,
+			SkipPersistContext: true,
+		}),
+	)
+
+	if _, err := a.Run(context.Background(), "What is Talos?", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	contextMsg := a.History[0]
+	if contextMsg.Role != llm.RoleSystem || contextMsg.Content != "[a] Talos is written in Go." {
+		t.Errorf("Unexpected context message: %+v", contextMsg)
+	}
+
+	history, _ := mem.Load(context.Background(), "s1")
+	if history[0].Role != llm.RoleUser || history[0].Content != "What is Talos?" {
+		t.Errorf("Expected raw user input in memory, got %+v", history[0])
+	}
+}
+
+func TestAgent_RAGPartialOptionsPersistContext(t *testing.T) {
+	mem := inmemory.New()
+	a := agent.New(&mockProvider{},
+		agent.WithKnowledge(newMockKnowledge()),
+		agent.WithMemory(mem, "s1"),
+		agent.WithRAGOptions(agent.RAGOptions{TopK: 1}),
+	)
+	if _, err := a.Run(context.Background(), "What is Talos?", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	history, _ := mem.Load(context.Background(), "s1")
+	if !strings.Contains(history[0].Content, "Relevant Context") {
+		t.Errorf("Expected context in saved user message, got %q", history[0].Content)
+	}
+}
+
+func TestAgent_RAGInvalidTemplate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected WithRAGOptions to panic on an invalid template")
+		}
+	}()
+	agent.New(&mockProvider{}, agent.WithRAGOptions(agent.RAGOptions{PromptTemplate: "{{.Query"}))
+}
+
+func TestAgent_RAGTransformedQuery(t *testing.T) {
+	transformer := query.TransformerFunc(func(ctx context.Context, q string, history []llm.Message) ([]string, error) {
+		return []string{q, "talos languages"}, nil
+	})
+	a := agent.New(&mockProvider{},
+		agent.WithKnowledge(newMockKnowledge()),
+		agent.WithRAGOptions(agent.RAGOptions{
+			PromptTemplate: "Queries:\n{{.Query}}",
+			Placement:      agent.PlacementSystemMessage,
+			QuerySource:    agent.QuerySourceTransformed,
+			Transformer:    transformer,
+		}),
+	)
+	if _, err := a.Run(context.Background(), "What is Talos?", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if got := a.History[0].Content; got != "Queries:\nWhat is Talos?\ntalos languages" {
+		t.Errorf("Expected the transformed queries in the context, got %q", got)
+	}
+}
+
+func TestAgent_RunResultCitations(t *testing.T) {
+	mock := &mockProvider{
+		responses: []llm.Message{
+			{Role: llm.RoleAssistant, Content: "Talos is written in Go [a]."},
+		},
+	}
+	a := agent.New(mock,
+		agent.WithKnowledge(newMockKnowledge()),
+		agent.WithRAGOptions(agent.RAGOptions{PromptTemplate: agent.CitationTemplate}),
+	)
+
+	result, err := a.RunResult(context.Background(), "What is Talos written in?", nil)
+	if err != nil {
+		t.Fatalf("RunResult failed: %v", err)
+	}
+
+	if len(result.Citations) != 2 {
+		t.Fatalf("Expected 2 citations, got %d", len(result.Citations))
+	}
+	if c := result.Citations[0]; c.DocumentID != "a" || !c.Referenced || c.Span.Text != "Talos is written in Go." {
+		t.Errorf("Unexpected first citation: %+v", c)
+	}
+	if result.Citations[1].Referenced {
+		t.Errorf("Expected second citation to be unreferenced")
+	}
+}
+
+func TestRecorder_DedupesByIDOrContent(t *testing.T) {
+	r := knowledge.NewRecorder()
+	r.Record(
+		knowledge.Document{ID: "a", Content: "one"},
+		knowledge.Document{ID: "a", Content: "one again"},
+		knowledge.Document{Content: "two"},
+		knowledge.Document{Content: "three"},
+		knowledge.Document{Content: "two"},
+	)
+
+	docs := r.Documents()
+	if len(docs) != 3 || docs[0].ID != "a" || docs[1].Content != "two" || docs[2].Content != "three" {
+		t.Errorf("Unexpected recorded documents: %+v", docs)
+	}
+}
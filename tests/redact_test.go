@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/barekit/talos/pkg/agent"
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory/inmemory"
+	"github.com/barekit/talos/pkg/redact"
+	"github.com/barekit/talos/pkg/tools"
+)
+
+func TestRedact_Detectors(t *testing.T) {
+	employee, err := redact.Pattern("EMPLOYEE_ID", `\bEMP-\d{6}\b`)
+	if err != nil {
+		t.Fatalf("Pattern failed: %v", err)
+	}
+	r := redact.New(redact.WithPatterns(employee))
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Mail jane.doe+work@example.co.uk now", "Mail [EMAIL] now"},
+		{"Call +44 20 7946 0958 or 555-123-4567", "Call [PHONE] or [PHONE]"},
+		{"Card 4111 1111 1111 1111 expires", "Card [CARD] expires"},
+		{"Not a card 4111 1111 1111 1112", "Not a card 4111 1111 1111 1112"},
+		{"IBAN DE89 3704 0044 0532 0130 00 please", "IBAN [IBAN] please"},
+		{"Bad IBAN DE00 3704 0044 0532 0130 00", "Bad IBAN DE00 3704 0044 0532 0130 00"},
+		{"Meeting on 2024-01-15 with EMP-123456", "Meeting on 2024-01-15 with [EMPLOYEE_ID]"},
+		{"Reach (020) 7946 0958 today", "Reach [PHONE] today"},
+		{"Request from 192.168.100.200 failed", "Request from 192.168.100.200 failed"},
+		{"Started at 2024-01-15 10:30:00", "Started at 2024-01-15 10:30:00"},
+		{"Logged 2024-01-15T10:30:00Z", "Logged 2024-01-15T10:30:00Z"},
+		{"Shipped order 123456789", "Shipped order 123456789"},
+		{"Version 1.22.333.4444", "Version 1.22.333.4444"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.input); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	if _, err := redact.Pattern("lower", `x`); err == nil {
+		t.Error("Expected an invalid detector name to be rejected")
+	}
+}
+
+func TestRedact_Tokenization(t *testing.T) {
+	r := redact.New(redact.WithTokenization(redact.NewMemoryVault()))
+
+	a := r.Redact("from jane@example.com to john@example.com")
+	b := r.Redact("reply to jane@example.com")
+	if strings.Contains(a, "@") || !strings.HasPrefix(a, "from [EMAIL_") {
+		t.Fatalf("Expected tokens, got %q", a)
+	}
+	if token := strings.TrimPrefix(b, "reply to "); !strings.Contains(a, token) {
+		t.Errorf("Expected the same value to get the same token: %q, %q", a, b)
+	}
+	if got := r.Restore(a); got != "from jane@example.com to john@example.com" {
+		t.Errorf("Restore = %q", got)
+	}
+	if got := r.Restore("[EMAIL_0000000000000000]"); got != "[EMAIL_0000000000000000]" {
+		t.Errorf("Expected unknown tokens to be kept, got %q", got)
+	}
+}
+
+func TestRedact_TokenizationSecret(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	a := redact.New(redact.WithTokenization(redact.NewMemoryVault()), redact.WithSecret(secret))
+	b := redact.New(redact.WithTokenization(redact.NewMemoryVault()), redact.WithSecret(secret))
+
+	if x, y := a.Redact("jane@example.com"), b.Redact("jane@example.com"); x != y {
+		t.Errorf("Expected the same secret to give the same token, got %q and %q", x, y)
+	}
+}
+
+type recordingProvider struct {
+	mockProvider
+	seen [][]llm.Message
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition) (*llm.Message, error) {
+	p.seen = append(p.seen, append([]llm.Message(nil), messages...))
+	return p.mockProvider.Chat(ctx, messages, tools)
+}
+
+func TestRedact_Agent(t *testing.T) {
+	r := redact.New(redact.WithTokenization(redact.NewMemoryVault()))
+	token := r.Redact("jane@example.com")
+
+	var received string
+	lookup, err := tools.New("Lookup", "Looks up a customer", func(args struct {
+		Email string `json:"email"`
+	}) (string, error) {
+		received = args.Email
+		return "Customer " + args.Email + " pays with 4111 1111 1111 1111", nil
+	})
+	if err != nil {
+		t.Fatalf("tools.New failed: %v", err)
+	}
+
+	provider := &recordingProvider{mockProvider: mockProvider{responses: []llm.Message{
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: llm.Function{Name: "Lookup", Arguments: `{"email":"` + token + `"}`},
+		}}},
+		{Role: llm.RoleAssistant, Content: "Done"},
+	}}}
+
+	mem := inmemory.New()
+	a := agent.New(provider,
+		agent.WithTools(lookup),
+		agent.WithMemory(mem, "s1"),
+		agent.WithRedactor(r, "Lookup"),
+	)
+	if _, err := a.Run(context.Background(), "Find the customer jane@example.com", nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if received != "jane@example.com" {
+		t.Errorf("Expected the allowed tool to see the real value, got %q", received)
+	}
+	for _, messages := range provider.seen {
+		for _, msg := range messages {
+			if strings.Contains(msg.Content, "jane@") || strings.Contains(msg.Content, "4111 1111") {
+				t.Errorf("Personal data reached the model: %q", msg.Content)
+			}
+		}
+	}
+	stored, _ := mem.Load(context.Background(), "s1")
+	for _, msg := range stored {
+		if strings.Contains(msg.Content, "jane@") || strings.Contains(msg.Content, "4111 1111") {
+			t.Errorf("Personal data reached memory: %q", msg.Content)
+		}
+	}
+}
+
+func TestRedact_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(redact.NewHandler(slog.NewTextHandler(&buf, nil), redact.New()))
+
+	logger.With("user", "jane@example.com").Info("login from +1 415 555 2671",
+		"error", errors.New("card 4111111111111111 declined"),
+		slog.Group("request", "iban", "DE89370400440532013000"),
+		"recipients", []string{"john@example.com"},
+		"attempts", 3,
+	)
+
+	out := buf.String()
+	for _, leaked := range []string{"jane@", "john@", "415 555", "4111", "DE89"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Log line leaked %q: %s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "[EMAIL]") || !strings.Contains(out, "request.iban=[IBAN]") || !strings.Contains(out, "attempts=3") {
+		t.Errorf("Expected redacted values in log line: %s", out)
+	}
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/local"
+	redisstore "github.com/barekit/talos/pkg/knowledge/redis"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisStore_Integration(t *testing.T) {
+	addr := os.Getenv("REDIS_STACK_ADDR")
+	if addr == "" {
+		t.Skip("Skipping Redis vector store integration test: REDIS_STACK_ADDR not set")
+	}
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: addr, Protocol: 2})
+	defer client.Close()
+
+	store, err := redisstore.New(ctx, client,
+		redisstore.WithIndexName("talos_test"),
+		redisstore.WithDimension(256),
+		redisstore.WithTagFields("topic"),
+		redisstore.WithNumericFields("version"),
+	)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer client.FTDropIndexWithArgs(ctx, "talos_test", &redis.FTDropIndexOptions{DeleteDocs: true})
+
+	embedder, _ := local.NewEmbedder(256)
+	kb := knowledge.NewKnowledgeBase(embedder, store)
+	docs := []knowledge.Document{
+		{ID: "go", Content: "Talos is an agent framework written in Go.", Metadata: map[string]interface{}{"topic": "intro", "version": 1}},
+		{ID: "rag", Content: "Vector stores include Qdrant and pgvector.", Metadata: map[string]interface{}{"topic": "rag", "version": 2}},
+	}
+	if err := kb.Ingest(ctx, docs); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	// Commas separate tags, so a tag containing one could not be matched.
+	comma := knowledge.Document{ID: "comma", Content: "Tags", Metadata: map[string]interface{}{"topic": "a,b"}}
+	if err := kb.Ingest(ctx, []knowledge.Document{comma}); err == nil {
+		t.Error("Expected a tag containing a comma to be rejected")
+	}
+
+	results, err := kb.Retrieve(ctx, "which vector stores are supported", 1)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "rag" {
+		t.Errorf("Expected rag, got %+v", results)
+	}
+
+	filtered, err := kb.RetrieveWithFilter(ctx, "vector stores", 5, knowledge.Filter{"version": 1})
+	if err != nil {
+		t.Fatalf("RetrieveWithFilter failed: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "go" {
+		t.Errorf("Expected only go, got %+v", filtered)
+	}
+
+	// Reopening with a different schema migrates the index and keeps the documents.
+	if _, err := redisstore.New(ctx, client,
+		redisstore.WithIndexName("talos_test"),
+		redisstore.WithDimension(256),
+		redisstore.WithFlatIndex(),
+		redisstore.WithTagFields("topic"),
+	); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/local"
+	"github.com/barekit/talos/pkg/knowledge/sqlite"
+)
+
+func TestSQLiteStore_SearchFilterDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "kb.db"))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	embedder, _ := local.NewEmbedder(256)
+	kb := knowledge.NewKnowledgeBase(embedder, store)
+	docs := []knowledge.Document{
+		{ID: "go", Content: "Talos is an agent framework written in Go.", Metadata: map[string]interface{}{"topic": "intro", "version": 1}},
+		{ID: "memory", Content: "Chat history can be stored in SQLite, Postgres, Redis or MongoDB.", Metadata: map[string]interface{}{"topic": "memory", "draft": true}},
+		{ID: "rag", Content: "Vector stores include Qdrant and pgvector.", Metadata: map[string]interface{}{"topic": "rag", "version": 2}},
+	}
+	if err := kb.Ingest(ctx, docs); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	results, err := kb.Retrieve(ctx, "which vector stores are supported", 2)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "rag" {
+		t.Fatalf("Expected rag first, got %+v", results)
+	}
+	if results[0].Score < results[1].Score {
+		t.Errorf("Expected results ordered by score, got %v then %v", results[0].Score, results[1].Score)
+	}
+	if results[0].Metadata["topic"] != "rag" {
+		t.Errorf("Expected metadata round-trip, got %v", results[0].Metadata)
+	}
+
+	filtered, err := kb.RetrieveWithFilter(ctx, "vector stores", 5, knowledge.Filter{"version": []interface{}{1, 3}})
+	if err != nil {
+		t.Fatalf("RetrieveWithFilter failed: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "go" {
+		t.Errorf("Expected only go for version filter, got %+v", filtered)
+	}
+
+	filtered, _ = kb.RetrieveWithFilter(ctx, "vector stores", 5, knowledge.Filter{"draft": true})
+	if len(filtered) != 1 || filtered[0].ID != "memory" {
+		t.Errorf("Expected only memory for draft filter, got %+v", filtered)
+	}
+
+	if err := store.Delete(ctx, []string{"rag"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, _ = kb.Retrieve(ctx, "which vector stores are supported", 5)
+	for _, doc := range results {
+		if doc.ID == "rag" {
+			t.Errorf("Expected rag to be deleted")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/knowledge/local"
+)
+
+func TestKnowledgeBase_Sync(t *testing.T) {
+	dir := t.TempDir()
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(docsDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	embedder, _ := local.NewEmbedder(64)
+	store := local.NewStore()
+	kb := knowledge.NewKnowledgeBase(embedder, store)
+
+	src := knowledge.DirSource{Root: docsDir, Extensions: []string{".md"}}
+	opts := knowledge.SyncOptions{Manifest: knowledge.FileManifest{Path: filepath.Join(dir, "manifest.json")}}
+	ctx := context.Background()
+
+	write("a.md", "Alpha")
+	write("b.md", "Bravo")
+	write("ignored.txt", "Ignored")
+
+	result, err := kb.Sync(ctx, src, opts)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Added != 2 || result.Chunks != 2 {
+		t.Errorf("Unexpected first sync result: %+v", result)
+	}
+
+	write("a.md", "Alpha, revised")
+	write("c.md", "Charlie")
+	if err := os.Remove(filepath.Join(docsDir, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = kb.Sync(ctx, src, opts)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	expected := knowledge.SyncResult{Added: 1, Updated: 1, Deleted: 1, Chunks: 2}
+	if *result != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *result)
+	}
+
+	docs, _ := store.Search(ctx, make([]float32, 64), 10)
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents in store, got %d", len(docs))
+	}
+	for _, doc := range docs {
+		if doc.ID == "b.md#0" {
+			t.Errorf("Expected b.md to be deleted")
+		}
+	}
+
+	result, err = kb.Sync(ctx, src, opts)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Unchanged != 2 || result.Chunks != 0 {
+		t.Errorf("Expected nothing to re-embed, got %+v", result)
+	}
+}
+
+func TestSplitBySize(t *testing.T) {
+	content := "one two three four five six seven eight nine ten"
+	chunks := knowledge.SplitBySize(16, 4)(content)
+	if len(chunks) < 3 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for _, c := range chunks {
+		if content[c.Start:c.End] != c.Text || len(c.Text) > 16 {
+			t.Errorf("Invalid chunk %+v", c)
+		}
+	}
+	if chunks[len(chunks)-1].End != len(content) {
+		t.Errorf("Expected chunks to cover the content")
+	}
+}
+
+func TestSplitBySize_Multibyte(t *testing.T) {
+	content := "日本語テキスト"
+	done := make(chan []knowledge.Chunk, 1)
+	go func() { done <- knowledge.SplitBySize(2, -1)(content) }()
+
+	var chunks []knowledge.Chunk
+	select {
+	case chunks = <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("SplitBySize did not return")
+	}
+
+	var joined string
+	for _, c := range chunks {
+		if c.Text == "" || !utf8.ValidString(c.Text) || content[c.Start:c.End] != c.Text {
+			t.Errorf("Invalid chunk %+v", c)
+		}
+		joined += c.Text
+	}
+	if joined != content {
+		t.Errorf("Expected chunks to cover the content, got %q", joined)
+	}
+}
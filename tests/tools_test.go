@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/barekit/talos/pkg/knowledge"
+	"github.com/barekit/talos/pkg/tools"
+)
+
+func TestKnowledgeSearchTool(t *testing.T) {
+	kb := knowledge.NewKnowledgeBase(&mockEmbedder{}, &mockStore{
+		docs: []knowledge.Document{
+			{ID: "a", Content: "Install with go get.", Score: 0.9, Metadata: map[string]interface{}{"section": "setup"}},
+			{ID: "b", Content: "Agents run tools.", Score: 0.8, Metadata: map[string]interface{}{"section": "agents"}},
+		},
+	})
+
+	tool, err := tools.NewKnowledgeSearch("search_docs", "Searches the product documentation", kb)
+	if err != nil {
+		t.Fatalf("Failed to create tool: %v", err)
+	}
+
+	params := tool.Definition.Function.Parameters.(map[string]interface{})
+	if required := params["required"].([]string); len(required) != 1 || required[0] != "query" {
+		t.Errorf("Expected only query to be required, got %v", required)
+	}
+
+	output, err := tool.CallContext(context.Background(), `{"query": "agents", "filter": {"section": "agents"}}`)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	var results []tools.KnowledgeSearchResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "b" || results[0].Score != 0.8 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/barekit/talos/pkg/llm"
+	"github.com/barekit/talos/pkg/memory"
+	"github.com/barekit/talos/pkg/memory/inmemory"
+	"github.com/barekit/talos/pkg/memory/sqlite"
+	"github.com/barekit/talos/pkg/memory/transfer"
+)
+
+func seedSessions(t *testing.T, m memory.Memory, ctx context.Context, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		id := "s" + string(rune('a'+i))
+		_ = m.Save(ctx, id, llm.Message{Role: llm.RoleUser, Content: "question " + id})
+		_ = m.Save(ctx, id, llm.Message{
+			Role:      llm.RoleAssistant,
+			ToolCalls: []llm.ToolCall{{ID: "c1", Type: "function", Function: llm.Function{Name: "lookup", Arguments: `{"q":1}`}}},
+		})
+		if err := m.(memory.SessionStore).SaveSession(ctx, memory.Session{ID: id, UserID: "u1", Title: "Session " + id}); err != nil {
+			t.Fatalf("SaveSession failed: %v", err)
+		}
+	}
+}
+
+func TestTransfer_ExportImport(t *testing.T) {
+	ctx := memory.WithTenant(context.Background(), "acme")
+	src := inmemory.New()
+	seedSessions(t, src, ctx, 3)
+
+	var buf bytes.Buffer
+	result, err := transfer.Export(ctx, src, &buf, transfer.Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result.Sessions != 3 || result.Messages != 6 {
+		t.Errorf("Unexpected export result: %+v", result)
+	}
+
+	dst, err := sqlite.New(filepath.Join(t.TempDir(), "import.db"))
+	if err != nil {
+		t.Fatalf("sqlite.New failed: %v", err)
+	}
+	result, err = transfer.Import(context.Background(), dst, &buf, transfer.Options{Verify: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Sessions != 3 || result.Verified != 3 {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	// Records keep their tenant.
+	msgs, _ := dst.Load(ctx, "sa")
+	if len(msgs) != 2 || msgs[1].ToolCalls[0].Function.Arguments != `{"q":1}` {
+		t.Errorf("Messages did not round-trip: %+v", msgs)
+	}
+	s, err := dst.GetSession(ctx, "sa")
+	if err != nil || s.Title != "Session sa" || s.UserID != "u1" {
+		t.Errorf("Session metadata did not round-trip: %+v, %v", s, err)
+	}
+}
+
+func TestTransfer_MigrateResume(t *testing.T) {
+	ctx := context.Background()
+	src := inmemory.New()
+	seedSessions(t, src, ctx, 4)
+	dst := inmemory.New()
+
+	// A run interrupted halfway through a session leaves a partial copy behind.
+	_ = dst.Save(ctx, "sb", llm.Message{Role: llm.RoleUser, Content: "question sb"})
+
+	checkpoint := &transfer.FileCheckpoint{Path: filepath.Join(t.TempDir(), "migrate.ckpt")}
+	result, err := transfer.Migrate(ctx, src, dst, transfer.Options{Checkpoint: checkpoint, Verify: true})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.Sessions != 4 || result.Messages != 7 || result.Verified != 4 {
+		t.Errorf("Unexpected migrate result: %+v", result)
+	}
+	if msgs, _ := dst.Load(ctx, "sb"); len(msgs) != 2 {
+		t.Errorf("Expected the partial session to be completed, got %d messages", len(msgs))
+	}
+
+	// A second run finds everything in a fresh checkpoint reader.
+	checkpoint = &transfer.FileCheckpoint{Path: checkpoint.Path}
+	result, err = transfer.Migrate(ctx, src, dst, transfer.Options{Checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.Sessions != 0 || result.Skipped != 4 {
+		t.Errorf("Expected all sessions to be skipped, got %+v", result)
+	}
+
+	// Extra messages in the destination fail verification.
+	_ = dst.Save(ctx, "sc", llm.Message{Role: llm.RoleUser, Content: "extra"})
+	if _, err := transfer.Migrate(ctx, src, dst, transfer.Options{}); !errors.Is(err, transfer.ErrVerification) {
+		t.Errorf("Expected ErrVerification, got %v", err)
+	}
+}
+
+func TestTransfer_MigrateDivergedPrefix(t *testing.T) {
+	ctx := context.Background()
+	src := inmemory.New()
+	seedSessions(t, src, ctx, 2)
+	dst := inmemory.New()
+
+	// The destination already holds a different conversation under the same ID.
+	_ = dst.Save(ctx, "sb", llm.Message{Role: llm.RoleUser, Content: "unrelated"})
+
+	_, err := transfer.Migrate(ctx, src, dst, transfer.Options{})
+	if !errors.Is(err, transfer.ErrVerification) {
+		t.Fatalf("Expected ErrVerification, got %v", err)
+	}
+	if msgs, _ := dst.Load(ctx, "sb"); len(msgs) != 1 {
+		t.Errorf("Expected the diverged session to be left alone, got %d messages", len(msgs))
+	}
+}